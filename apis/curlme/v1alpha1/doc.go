@@ -0,0 +1,5 @@
+// +k8s:deepcopy-gen=package
+// +groupName=curlme.x-k8s.io
+
+// Package v1alpha1 is the v1alpha1 version of the curlme.x-k8s.io API.
+package v1alpha1
@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	TargetConfigMap = "ConfigMap"
+	TargetSecret    = "Secret"
+
+	TransformTrimSpace    = "TrimSpace"
+	TransformBase64Decode = "Base64Decode"
+	TransformJSONPath     = "JSONPath"
+
+	// ConditionFetched is set on each source, ConditionReady on the
+	// RemoteData once every source has been written to the target.
+	ConditionFetched = "Fetched"
+	ConditionReady   = "Ready"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RemoteData fetches the contents of urls into a ConfigMap or Secret it owns.
+type RemoteData struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RemoteDataSpec   `json:"spec"`
+	Status RemoteDataStatus `json:"status,omitempty"`
+}
+
+type RemoteDataSpec struct {
+	Target  Target   `json:"target"`
+	Sources []Source `json:"sources"`
+}
+
+// Target is the object in the RemoteData's namespace the sources are written
+// to. It is created if missing and must not be owned by anything else.
+type Target struct {
+	// Kind is ConfigMap or Secret.
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Source is fetched into a single key of the target.
+type Source struct {
+	Key string `json:"key"`
+	URL string `json:"url"`
+	// +optional
+	Headers []Header `json:"headers,omitempty"`
+	// Transforms are applied to the response body in order.
+	// +optional
+	Transforms []Transform `json:"transforms,omitempty"`
+	// RefreshInterval is how often the source is fetched again. It is only
+	// fetched when the spec changes when unset.
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
+}
+
+// Header is a request header, its value set either in Value or read from a
+// Secret in the RemoteData's namespace with ValueFrom.
+type Header struct {
+	Name string `json:"name"`
+	// +optional
+	Value string `json:"value,omitempty"`
+	// +optional
+	ValueFrom *HeaderSource `json:"valueFrom,omitempty"`
+}
+
+type HeaderSource struct {
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+type Transform struct {
+	// Type is TrimSpace, Base64Decode or JSONPath.
+	Type string `json:"type"`
+	// Expression is the JSONPath expression, e.g. {.data.value}.
+	// +optional
+	Expression string `json:"expression,omitempty"`
+}
+
+type RemoteDataStatus struct {
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+	// +optional
+	Sources []SourceStatus `json:"sources,omitempty"`
+}
+
+type SourceStatus struct {
+	Key string `json:"key"`
+	// +optional
+	LastFetchTime *metav1.Time `json:"lastFetchTime,omitempty"`
+	// +optional
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+type Condition struct {
+	Type   string                 `json:"type"`
+	Status metav1.ConditionStatus `json:"status"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type RemoteDataList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []RemoteData `json:"items"`
+}
@@ -0,0 +1,24 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/aclevername/config-map-controller/client/clientset/versioned/typed/curlme/v1alpha1"
+	rest "k8s.io/client-go/rest"
+	testing "k8s.io/client-go/testing"
+)
+
+type FakeCurlmeV1alpha1 struct {
+	*testing.Fake
+}
+
+func (c *FakeCurlmeV1alpha1) RemoteDatas(namespace string) v1alpha1.RemoteDataInterface {
+	return &FakeRemoteDatas{c, namespace}
+}
+
+// RESTClient returns a RESTClient that is used to communicate
+// with API server by this client implementation.
+func (c *FakeCurlmeV1alpha1) RESTClient() rest.Interface {
+	var ret *rest.RESTClient
+	return ret
+}
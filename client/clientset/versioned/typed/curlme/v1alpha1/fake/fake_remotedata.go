@@ -0,0 +1,124 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/aclevername/config-map-controller/apis/curlme/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeRemoteDatas implements RemoteDataInterface
+type FakeRemoteDatas struct {
+	Fake *FakeCurlmeV1alpha1
+	ns   string
+}
+
+var remotedatasResource = schema.GroupVersionResource{Group: "curlme.x-k8s.io", Version: "v1alpha1", Resource: "remotedatas"}
+
+var remotedatasKind = schema.GroupVersionKind{Group: "curlme.x-k8s.io", Version: "v1alpha1", Kind: "RemoteData"}
+
+// Get takes name of the remoteData, and returns the corresponding remoteData object, and an error if there is any.
+func (c *FakeRemoteDatas) Get(name string, options v1.GetOptions) (result *v1alpha1.RemoteData, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(remotedatasResource, c.ns, name), &v1alpha1.RemoteData{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RemoteData), err
+}
+
+// List takes label and field selectors, and returns the list of RemoteDatas that match those selectors.
+func (c *FakeRemoteDatas) List(opts v1.ListOptions) (result *v1alpha1.RemoteDataList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(remotedatasResource, remotedatasKind, c.ns, opts), &v1alpha1.RemoteDataList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.RemoteDataList{ListMeta: obj.(*v1alpha1.RemoteDataList).ListMeta}
+	for _, item := range obj.(*v1alpha1.RemoteDataList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested remoteDatas.
+func (c *FakeRemoteDatas) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(remotedatasResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a remoteData and creates it.  Returns the server's representation of the remoteData, and an error, if there is any.
+func (c *FakeRemoteDatas) Create(remoteData *v1alpha1.RemoteData) (result *v1alpha1.RemoteData, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(remotedatasResource, c.ns, remoteData), &v1alpha1.RemoteData{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RemoteData), err
+}
+
+// Update takes the representation of a remoteData and updates it. Returns the server's representation of the remoteData, and an error, if there is any.
+func (c *FakeRemoteDatas) Update(remoteData *v1alpha1.RemoteData) (result *v1alpha1.RemoteData, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(remotedatasResource, c.ns, remoteData), &v1alpha1.RemoteData{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RemoteData), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeRemoteDatas) UpdateStatus(remoteData *v1alpha1.RemoteData) (*v1alpha1.RemoteData, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(remotedatasResource, "status", c.ns, remoteData), &v1alpha1.RemoteData{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RemoteData), err
+}
+
+// Delete takes name of the remoteData and deletes it. Returns an error if one occurs.
+func (c *FakeRemoteDatas) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(remotedatasResource, c.ns, name), &v1alpha1.RemoteData{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeRemoteDatas) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(remotedatasResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.RemoteDataList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched remoteData.
+func (c *FakeRemoteDatas) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.RemoteData, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(remotedatasResource, c.ns, name, pt, data, subresources...), &v1alpha1.RemoteData{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.RemoteData), err
+}
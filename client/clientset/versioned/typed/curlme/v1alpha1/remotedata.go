@@ -0,0 +1,175 @@
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/aclevername/config-map-controller/apis/curlme/v1alpha1"
+	scheme "github.com/aclevername/config-map-controller/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// RemoteDatasGetter has a method to return a RemoteDataInterface.
+// A group's client should implement this interface.
+type RemoteDatasGetter interface {
+	RemoteDatas(namespace string) RemoteDataInterface
+}
+
+// RemoteDataInterface has methods to work with RemoteData resources.
+type RemoteDataInterface interface {
+	Create(*v1alpha1.RemoteData) (*v1alpha1.RemoteData, error)
+	Update(*v1alpha1.RemoteData) (*v1alpha1.RemoteData, error)
+	UpdateStatus(*v1alpha1.RemoteData) (*v1alpha1.RemoteData, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.RemoteData, error)
+	List(opts v1.ListOptions) (*v1alpha1.RemoteDataList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.RemoteData, err error)
+	RemoteDataExpansion
+}
+
+// remoteDatas implements RemoteDataInterface
+type remoteDatas struct {
+	client rest.Interface
+	ns     string
+}
+
+// newRemoteDatas returns a RemoteDatas
+func newRemoteDatas(c *CurlmeV1alpha1Client, namespace string) *remoteDatas {
+	return &remoteDatas{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the remoteData, and returns the corresponding remoteData object, and an error if there is any.
+func (c *remoteDatas) Get(name string, options v1.GetOptions) (result *v1alpha1.RemoteData, err error) {
+	result = &v1alpha1.RemoteData{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("remotedatas").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of RemoteDatas that match those selectors.
+func (c *remoteDatas) List(opts v1.ListOptions) (result *v1alpha1.RemoteDataList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.RemoteDataList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("remotedatas").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested remoteDatas.
+func (c *remoteDatas) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("remotedatas").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a remoteData and creates it.  Returns the server's representation of the remoteData, and an error, if there is any.
+func (c *remoteDatas) Create(remoteData *v1alpha1.RemoteData) (result *v1alpha1.RemoteData, err error) {
+	result = &v1alpha1.RemoteData{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("remotedatas").
+		Body(remoteData).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a remoteData and updates it. Returns the server's representation of the remoteData, and an error, if there is any.
+func (c *remoteDatas) Update(remoteData *v1alpha1.RemoteData) (result *v1alpha1.RemoteData, err error) {
+	result = &v1alpha1.RemoteData{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("remotedatas").
+		Name(remoteData.Name).
+		Body(remoteData).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *remoteDatas) UpdateStatus(remoteData *v1alpha1.RemoteData) (result *v1alpha1.RemoteData, err error) {
+	result = &v1alpha1.RemoteData{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("remotedatas").
+		Name(remoteData.Name).
+		SubResource("status").
+		Body(remoteData).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the remoteData and deletes it. Returns an error if one occurs.
+func (c *remoteDatas) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("remotedatas").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *remoteDatas) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("remotedatas").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched remoteData.
+func (c *remoteDatas) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.RemoteData, err error) {
+	result = &v1alpha1.RemoteData{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("remotedatas").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
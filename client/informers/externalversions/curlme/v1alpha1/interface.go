@@ -0,0 +1,29 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	internalinterfaces "github.com/aclevername/config-map-controller/client/informers/externalversions/internalinterfaces"
+)
+
+// Interface provides access to all the informers in this group version.
+type Interface interface {
+	// RemoteDatas returns a RemoteDataInformer.
+	RemoteDatas() RemoteDataInformer
+}
+
+type version struct {
+	factory          internalinterfaces.SharedInformerFactory
+	namespace        string
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// New returns a new Interface.
+func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakListOptions internalinterfaces.TweakListOptionsFunc) Interface {
+	return &version{factory: f, namespace: namespace, tweakListOptions: tweakListOptions}
+}
+
+// RemoteDatas returns a RemoteDataInformer.
+func (v *version) RemoteDatas() RemoteDataInformer {
+	return &remoteDataInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
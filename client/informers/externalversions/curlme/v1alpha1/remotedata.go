@@ -0,0 +1,73 @@
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	time "time"
+
+	curlmev1alpha1 "github.com/aclevername/config-map-controller/apis/curlme/v1alpha1"
+	versioned "github.com/aclevername/config-map-controller/client/clientset/versioned"
+	internalinterfaces "github.com/aclevername/config-map-controller/client/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/aclevername/config-map-controller/client/listers/curlme/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// RemoteDataInformer provides access to a shared informer and lister for
+// RemoteDatas.
+type RemoteDataInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.RemoteDataLister
+}
+
+type remoteDataInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewRemoteDataInformer constructs a new informer for RemoteData type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewRemoteDataInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredRemoteDataInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredRemoteDataInformer constructs a new informer for RemoteData type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredRemoteDataInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CurlmeV1alpha1().RemoteDatas(namespace).List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CurlmeV1alpha1().RemoteDatas(namespace).Watch(options)
+			},
+		},
+		&curlmev1alpha1.RemoteData{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *remoteDataInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredRemoteDataInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *remoteDataInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&curlmev1alpha1.RemoteData{}, f.defaultInformer)
+}
+
+func (f *remoteDataInformer) Lister() v1alpha1.RemoteDataLister {
+	return v1alpha1.NewRemoteDataLister(f.Informer().GetIndexer())
+}
@@ -0,0 +1,11 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+// RemoteDataListerExpansion allows custom methods to be added to
+// RemoteDataLister.
+type RemoteDataListerExpansion interface{}
+
+// RemoteDataNamespaceListerExpansion allows custom methods to be added to
+// RemoteDataNamespaceLister.
+type RemoteDataNamespaceListerExpansion interface{}
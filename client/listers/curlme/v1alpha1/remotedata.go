@@ -0,0 +1,78 @@
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/aclevername/config-map-controller/apis/curlme/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// RemoteDataLister helps list RemoteDatas.
+type RemoteDataLister interface {
+	// List lists all RemoteDatas in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.RemoteData, err error)
+	// RemoteDatas returns an object that can list and get RemoteDatas.
+	RemoteDatas(namespace string) RemoteDataNamespaceLister
+	RemoteDataListerExpansion
+}
+
+// remoteDataLister implements the RemoteDataLister interface.
+type remoteDataLister struct {
+	indexer cache.Indexer
+}
+
+// NewRemoteDataLister returns a new RemoteDataLister.
+func NewRemoteDataLister(indexer cache.Indexer) RemoteDataLister {
+	return &remoteDataLister{indexer: indexer}
+}
+
+// List lists all RemoteDatas in the indexer.
+func (s *remoteDataLister) List(selector labels.Selector) (ret []*v1alpha1.RemoteData, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.RemoteData))
+	})
+	return ret, err
+}
+
+// RemoteDatas returns an object that can list and get RemoteDatas.
+func (s *remoteDataLister) RemoteDatas(namespace string) RemoteDataNamespaceLister {
+	return remoteDataNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// RemoteDataNamespaceLister helps list and get RemoteDatas.
+type RemoteDataNamespaceLister interface {
+	// List lists all RemoteDatas in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.RemoteData, err error)
+	// Get retrieves the RemoteData from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.RemoteData, error)
+	RemoteDataNamespaceListerExpansion
+}
+
+// remoteDataNamespaceLister implements the RemoteDataNamespaceLister
+// interface.
+type remoteDataNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all RemoteDatas in the indexer for a given namespace.
+func (s remoteDataNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.RemoteData, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.RemoteData))
+	})
+	return ret, err
+}
+
+// Get retrieves the RemoteData from the indexer for a given namespace and name.
+func (s remoteDataNamespaceLister) Get(name string) (*v1alpha1.RemoteData, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("remotedata"), name)
+	}
+	return obj.(*v1alpha1.RemoteData), nil
+}
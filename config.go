@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aclevername/config-map-controller/config"
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/webhook"
+)
+
+// configFileFlags maps the flags a config file replaces to the field that
+// replaces them.
+var configFileFlags = map[string]string{
+	"namespaces":               "scope.namespaces",
+	"exclude-namespaces":       "scope.excludeNamespaces",
+	"label-selector":           "scope.labelSelector",
+	"namespace-label-selector": "scope.namespaceLabelSelector",
+	"metadata-only":            "scope.metadataOnly",
+	"allowed-schemes":          "policy.allowedSchemes",
+	"allowed-hosts":            "policy.allowedHosts",
+	"denied-hosts":             "policy.deniedHosts",
+	"log-format":               "logging.format",
+	"log-level":                "logging.level",
+	"history-revisions":        "history.revisions",
+}
+
+// loadConfig loads file when it is set, refusing flags the file replaces.
+// Otherwise the defaults are used with the flags applied.
+func loadConfig(flags *flag.FlagSet, file string, fromFlags func() config.Config) (config.Config, error) {
+	if file == "" {
+		return fromFlags(), nil
+	}
+
+	var conflicts []string
+	flags.Visit(func(f *flag.Flag) {
+		if field, ok := configFileFlags[f.Name]; ok {
+			conflicts = append(conflicts, fmt.Sprintf("-%s can't be used with -config, set %s in the config file instead", f.Name, field))
+		}
+	})
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return config.Config{}, fmt.Errorf("%s", conflicts[0])
+	}
+
+	return config.Load(file)
+}
+
+// configureLog applies the log level from spec, falling back to $LOG_LEVEL
+// when it is empty.
+func configureLog(spec string) error {
+	if spec == "" {
+		spec = os.Getenv("LOG_LEVEL")
+	}
+	return log.Configure(spec)
+}
+
+// watchConfig applies the settings that are safe to change while running
+// whenever file changes, and logs the changes that need a restart.
+func watchConfig(ctx context.Context, file string, interval time.Duration, current config.Config, r *reconciler.ConfigMapReconciler, validator *webhook.Validator) {
+	config.Watch(ctx, file, interval, current, func(old, new config.Config) {
+		r.SetPolicy(new.Policy)
+		r.SetFetchOptions(new.FetchOptions())
+		if validator != nil {
+			validator.SetPolicy(new.Policy)
+		}
+		if err := configureLog(new.Logging.Level); err != nil {
+			log.Error("failed to apply log level from config file: %v", err)
+		}
+
+		for _, field := range config.RestartRequired(old, new) {
+			log.Info("%s changed in config file %s, restart to apply it", field, file)
+		}
+		log.Info("reloaded config file %s", file)
+	})
+}
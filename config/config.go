@@ -0,0 +1,217 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/scope"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	APIVersion = "curlme.x-k8s.io/v1alpha1"
+	Kind       = "ControllerConfig"
+
+	DefaultAnnotationKey = "x-k8s.io/curl-me-that"
+
+	// minRefreshInterval stops a typo from refetching every configmap in a
+	// tight loop.
+	minRefreshInterval = 10 * time.Second
+)
+
+// Config is the controller configuration file. Fields left out of the file
+// keep their defaults.
+type Config struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Annotation Annotation        `json:"annotation"`
+	Scope      Scope             `json:"scope"`
+	HTTP       HTTP              `json:"http"`
+	Policy     reconciler.Policy `json:"policy"`
+	Limits     Limits            `json:"limits"`
+	Refresh    Refresh           `json:"refresh"`
+	History    History           `json:"history"`
+	Workers    int               `json:"workers"`
+	Logging    Logging           `json:"logging"`
+}
+
+type Annotation struct {
+	Key string `json:"key"`
+}
+
+type Scope struct {
+	scope.Scope  `json:",inline"`
+	MetadataOnly bool `json:"metadataOnly"`
+}
+
+type HTTP struct {
+	Timeout   metav1.Duration `json:"timeout"`
+	UserAgent string          `json:"userAgent"`
+}
+
+type Limits struct {
+	MaxResponseBytes int64 `json:"maxResponseBytes"`
+}
+
+// Refresh controls refetching data keys that are already set. Zero disables
+// it.
+type Refresh struct {
+	Interval metav1.Duration `json:"interval"`
+}
+
+// History controls how many fetched revisions of each data key are kept for
+// rollbacks. Zero disables it.
+type History struct {
+	Revisions int `json:"revisions"`
+}
+
+type Logging struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+func Default() Config {
+	return Config{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Annotation: Annotation{Key: DefaultAnnotationKey},
+		HTTP: HTTP{
+			Timeout:   metav1.Duration{Duration: 30 * time.Second},
+			UserAgent: "config-map-controller",
+		},
+		Policy:  reconciler.Policy{AllowedSchemes: []string{"http", "https"}},
+		Limits:  Limits{MaxResponseBytes: 1024 * 1024},
+		Workers: 1,
+		Logging: Logging{Format: log.FormatText},
+	}
+}
+
+// Load reads and validates a config file, filling in defaults for the
+// fields it leaves out.
+func Load(file string) (Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config file: %v", err)
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (Config, error) {
+	config := Default()
+	config.APIVersion = ""
+	config.Kind = ""
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to decode config: %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate returns every problem with the config, each prefixed with the
+// path of the field it concerns.
+func (c Config) Validate() error {
+	var problems []string
+	add := func(field, format string, v ...interface{}) {
+		problems = append(problems, field+": "+fmt.Sprintf(format, v...))
+	}
+
+	if c.APIVersion != APIVersion {
+		add("apiVersion", "expected %s, got '%s'", APIVersion, c.APIVersion)
+	}
+	if c.Kind != Kind {
+		add("kind", "expected %s, got '%s'", Kind, c.Kind)
+	}
+
+	if errs := validation.IsQualifiedName(c.Annotation.Key); len(errs) > 0 {
+		add("annotation.key", "invalid annotation key '%s': %s", c.Annotation.Key, strings.Join(errs, ", "))
+	}
+
+	if err := c.Scope.Validate(); err != nil {
+		add("scope", "%v", err)
+	}
+
+	if c.HTTP.Timeout.Duration < 0 {
+		add("http.timeout", "must not be negative, got %s", c.HTTP.Timeout.Duration)
+	}
+
+	for i, scheme := range c.Policy.AllowedSchemes {
+		if scheme == "" {
+			add(fmt.Sprintf("policy.allowedSchemes[%d]", i), "must not be empty")
+		}
+	}
+	validateHosts := func(field string, hosts []string) {
+		for i, host := range hosts {
+			if _, err := path.Match(host, ""); err != nil || host == "" {
+				add(fmt.Sprintf("%s[%d]", field, i), "invalid host pattern '%s'", host)
+			}
+		}
+	}
+	validateHosts("policy.allowedHosts", c.Policy.AllowedHosts)
+	validateHosts("policy.deniedHosts", c.Policy.DeniedHosts)
+
+	if c.Limits.MaxResponseBytes < 0 {
+		add("limits.maxResponseBytes", "must not be negative, got %d", c.Limits.MaxResponseBytes)
+	}
+
+	if interval := c.Refresh.Interval.Duration; interval != 0 && interval < minRefreshInterval {
+		add("refresh.interval", "must be 0 to disable refreshing or at least %s, got %s", minRefreshInterval, interval)
+	}
+
+	if c.History.Revisions < 0 {
+		add("history.revisions", "must not be negative, got %d", c.History.Revisions)
+	}
+
+	if c.Workers < 1 {
+		add("workers", "must be at least 1, got %d", c.Workers)
+	}
+
+	if err := log.ValidateSpec(c.Logging.Level); err != nil {
+		add("logging.level", "%v", err)
+	}
+	if c.Logging.Format != log.FormatText && c.Logging.Format != log.FormatJSON {
+		add("logging.format", "expected %s or %s, got '%s'", log.FormatText, log.FormatJSON, c.Logging.Format)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func (c Config) FetchOptions() reconciler.FetchOptions {
+	return reconciler.FetchOptions{
+		Timeout:          c.HTTP.Timeout.Duration,
+		UserAgent:        c.HTTP.UserAgent,
+		MaxResponseBytes: c.Limits.MaxResponseBytes,
+	}
+}
+
+// RestartRequired returns the fields that differ between old and new but
+// only take effect once the controller restarts. Every other field can be
+// applied while running.
+func RestartRequired(old, new Config) []string {
+	var fields []string
+	changed := func(field string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			fields = append(fields, field)
+		}
+	}
+	changed("annotation", old.Annotation, new.Annotation)
+	changed("scope", old.Scope, new.Scope)
+	changed("refresh", old.Refresh, new.Refresh)
+	changed("history", old.History, new.History)
+	changed("workers", old.Workers, new.Workers)
+	changed("logging.format", old.Logging.Format, new.Logging.Format)
+	return fields
+}
@@ -0,0 +1,178 @@
+package config_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/config"
+	"github.com/aclevername/config-map-controller/reconciler"
+)
+
+var _ = Describe("Parse", func() {
+	It("fills in defaults for fields left out", func() {
+		cfg, err := config.Parse([]byte(`apiVersion: curlme.x-k8s.io/v1alpha1
+kind: ControllerConfig
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg).To(Equal(config.Default()))
+	})
+
+	It("reads every section", func() {
+		cfg, err := config.Parse([]byte(`apiVersion: curlme.x-k8s.io/v1alpha1
+kind: ControllerConfig
+annotation:
+  key: example.com/fetch
+scope:
+  namespaces: [team-a]
+  labelSelector: app=web
+  namespaceLabelSelector: env=prod
+  metadataOnly: true
+http:
+  timeout: 5s
+  userAgent: my-agent
+policy:
+  allowedHosts: ["*.example.com"]
+limits:
+  maxResponseBytes: 2048
+refresh:
+  interval: 1m
+history:
+  revisions: 5
+workers: 4
+logging:
+  level: info,reconciler=debug
+  format: json
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Annotation.Key).To(Equal("example.com/fetch"))
+		Expect(cfg.Scope.Namespaces).To(Equal([]string{"team-a"}))
+		Expect(cfg.Scope.LabelSelector).To(Equal("app=web"))
+		Expect(cfg.Scope.NamespaceSelector).To(Equal("env=prod"))
+		Expect(cfg.Scope.MetadataOnly).To(BeTrue())
+		Expect(cfg.Policy).To(Equal(reconciler.Policy{
+			AllowedSchemes: []string{"http", "https"},
+			AllowedHosts:   []string{"*.example.com"},
+		}))
+		Expect(cfg.FetchOptions()).To(Equal(reconciler.FetchOptions{
+			Timeout:          5 * time.Second,
+			UserAgent:        "my-agent",
+			MaxResponseBytes: 2048,
+		}))
+		Expect(cfg.Refresh.Interval.Duration).To(Equal(time.Minute))
+		Expect(cfg.History.Revisions).To(Equal(5))
+		Expect(cfg.Workers).To(Equal(4))
+		Expect(cfg.Logging).To(Equal(config.Logging{Level: "info,reconciler=debug", Format: "json"}))
+	})
+
+	It("rejects unknown fields", func() {
+		_, err := config.Parse([]byte(`apiVersion: curlme.x-k8s.io/v1alpha1
+kind: ControllerConfig
+worker: 2
+`))
+		Expect(err).To(MatchError(ContainSubstring(`unknown field "worker"`)))
+	})
+
+	It("reports every invalid field", func() {
+		_, err := config.Parse([]byte(`apiVersion: v1
+kind: ControllerConfig
+annotation:
+  key: "not a key"
+scope:
+  labelSelector: "a in"
+http:
+  timeout: -1s
+policy:
+  allowedSchemes: [""]
+  deniedHosts: ["["]
+limits:
+  maxResponseBytes: -1
+refresh:
+  interval: 1s
+history:
+  revisions: -1
+workers: 0
+logging:
+  level: loud
+  format: xml
+`))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix("invalid config:\n"))
+		Expect(err.Error()).To(ContainSubstring("\n  apiVersion: expected curlme.x-k8s.io/v1alpha1, got 'v1'"))
+		Expect(err.Error()).To(ContainSubstring("\n  annotation.key: invalid annotation key 'not a key'"))
+		Expect(err.Error()).To(ContainSubstring("\n  scope: invalid label selector 'a in'"))
+		Expect(err.Error()).To(ContainSubstring("\n  http.timeout: must not be negative, got -1s"))
+		Expect(err.Error()).To(ContainSubstring("\n  policy.allowedSchemes[0]: must not be empty"))
+		Expect(err.Error()).To(ContainSubstring("\n  policy.deniedHosts[0]: invalid host pattern '['"))
+		Expect(err.Error()).To(ContainSubstring("\n  limits.maxResponseBytes: must not be negative, got -1"))
+		Expect(err.Error()).To(ContainSubstring("\n  refresh.interval: must be 0 to disable refreshing or at least 10s, got 1s"))
+		Expect(err.Error()).To(ContainSubstring("\n  history.revisions: must not be negative, got -1"))
+		Expect(err.Error()).To(ContainSubstring("\n  workers: must be at least 1, got 0"))
+		Expect(err.Error()).To(ContainSubstring("\n  logging.level: unknown log level 'loud', expected debug, info or error"))
+		Expect(err.Error()).To(ContainSubstring("\n  logging.format: expected text or json, got 'xml'"))
+	})
+})
+
+var _ = Describe("RestartRequired", func() {
+	It("lists the changed fields that can't be applied while running", func() {
+		old := config.Default()
+		new := config.Default()
+		new.Policy.DeniedHosts = []string{"localhost"}
+		new.HTTP.UserAgent = "other"
+		new.Logging.Level = "debug"
+		Expect(config.RestartRequired(old, new)).To(BeEmpty())
+
+		new.Workers = 2
+		new.Scope.Namespaces = []string{"team-a"}
+		Expect(config.RestartRequired(old, new)).To(Equal([]string{"scope", "workers"}))
+	})
+})
+
+var _ = Describe("Watch", func() {
+	var (
+		dir    string
+		file   string
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	write := func(content string) {
+		Expect(ioutil.WriteFile(file, []byte("apiVersion: curlme.x-k8s.io/v1alpha1\nkind: ControllerConfig\n"+content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "config")
+		Expect(err).NotTo(HaveOccurred())
+		file = filepath.Join(dir, "config.yaml")
+		write("")
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("calls back with valid changes and skips invalid ones", func() {
+		changes := make(chan config.Config, 10)
+		go config.Watch(ctx, file, 10*time.Millisecond, config.Default(), func(old, new config.Config) {
+			changes <- new
+		})
+
+		Consistently(changes, 50*time.Millisecond).ShouldNot(Receive())
+
+		write("workers: 0\n")
+		Consistently(changes, 50*time.Millisecond).ShouldNot(Receive())
+
+		write("workers: 3\n")
+		var cfg config.Config
+		Eventually(changes).Should(Receive(&cfg))
+		Expect(cfg.Workers).To(Equal(3))
+	})
+})
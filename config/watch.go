@@ -0,0 +1,47 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"time"
+
+	"github.com/aclevername/config-map-controller/log"
+)
+
+// Watch polls file every interval until ctx is done, calling onChange with
+// the previous and new config whenever its content changes. Polling rather
+// than watching for events copes with a file mounted from a ConfigMap being
+// replaced through a symlink. Invalid changes are logged and skipped, keeping
+// the last good config.
+func Watch(ctx context.Context, file string, interval time.Duration, current Config, onChange func(old, new Config)) {
+	last, _ := ioutil.ReadFile(file)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			log.Error("failed to read config file %s: %v", file, err)
+			continue
+		}
+		if bytes.Equal(data, last) {
+			continue
+		}
+		last = data
+
+		config, err := Parse(data)
+		if err != nil {
+			log.Error("ignoring change to config file %s: %v", file, err)
+			continue
+		}
+		onChange(current, config)
+		current = config
+	}
+}
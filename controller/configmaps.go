@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/cache"
+)
+
+//go:generate counterfeiter -o fakes/fake_config_map_reconciler.go . ConfigMapReconciler
+
+type ConfigMapReconciler interface {
+	ReconcileResource(ctx context.Context, cm *apiv1.ConfigMap) error
+}
+
+// DeletedConfigMapReconciler is implemented by ConfigMapReconcilers that clean
+// up after ConfigMaps once they are deleted.
+type DeletedConfigMapReconciler interface {
+	ReconcileDeleted(ctx context.Context, namespace, name string) error
+}
+
+// RefreshingConfigMapReconciler is implemented by ConfigMapReconcilers that
+// fetch data again periodically. RefreshAfter returns how long until the
+// configmap is next due, zero when it isn't refreshed.
+type RefreshingConfigMapReconciler interface {
+	RefreshAfter(cm *apiv1.ConfigMap) time.Duration
+}
+
+// ConfigMapGetter returns a ConfigMap, or a NotFound error once it has been
+// deleted.
+type ConfigMapGetter func(namespace, name string) (*apiv1.ConfigMap, error)
+
+// ConfigMaps reconciles ConfigMaps by key with reconciler, looking each up
+// with get. Keys of deleted ConfigMaps are skipped, or passed to
+// ReconcileDeleted when reconciler is a DeletedConfigMapReconciler. Keys are
+// requeued once they are due when reconciler is a
+// RefreshingConfigMapReconciler.
+func ConfigMaps(get ConfigMapGetter, reconciler ConfigMapReconciler) Reconciler {
+	return configMaps{get: get, reconciler: reconciler}
+}
+
+type configMaps struct {
+	get        ConfigMapGetter
+	reconciler ConfigMapReconciler
+}
+
+func (c configMaps) Reconcile(ctx context.Context, key string) (Result, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return Result{}, err
+	}
+
+	configMap, err := c.get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		if deleted, ok := c.reconciler.(DeletedConfigMapReconciler); ok {
+			return Result{}, deleted.ReconcileDeleted(ctx, namespace, name)
+		}
+		return Result{}, nil
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	err = c.reconciler.ReconcileResource(ctx, configMap)
+	if refreshing, ok := c.reconciler.(RefreshingConfigMapReconciler); ok {
+		return Result{RequeueAfter: refreshing.RefreshAfter(configMap)}, err
+	}
+	return Result{}, err
+}
@@ -0,0 +1,102 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aclevername/config-map-controller/controller"
+	"github.com/aclevername/config-map-controller/controller/fakes"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConfigMaps", func() {
+	var (
+		fakeReconciler *fakes.FakeConfigMapReconciler
+		configMap      *apiv1.ConfigMap
+		getErr         error
+		reconciler     controller.Reconciler
+	)
+
+	BeforeEach(func() {
+		fakeReconciler = new(fakes.FakeConfigMapReconciler)
+		configMap = &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "configmap"}}
+		getErr = nil
+		reconciler = controller.ConfigMaps(func(namespace, name string) (*apiv1.ConfigMap, error) {
+			Expect(namespace).To(Equal("team-a"))
+			Expect(name).To(Equal("configmap"))
+			return configMap, getErr
+		}, fakeReconciler)
+	})
+
+	It("reconciles the configmap with the key", func() {
+		fakeReconciler.ReconcileResourceReturns(errors.New("failed"))
+		result, err := reconciler.Reconcile(context.Background(), "team-a/configmap")
+		Expect(err).To(MatchError("failed"))
+		Expect(result).To(Equal(controller.Result{}))
+		_, reconciled := fakeReconciler.ReconcileResourceArgsForCall(0)
+		Expect(reconciled).To(Equal(configMap))
+	})
+
+	It("skips deleted configmaps", func() {
+		getErr = apierrors.NewNotFound(apiv1.Resource("configmaps"), "configmap")
+		_, err := reconciler.Reconcile(context.Background(), "team-a/configmap")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeReconciler.ReconcileResourceCallCount()).To(Equal(0))
+	})
+
+	It("passes deleted configmaps to reconcilers cleaning up after them", func() {
+		deleted := &deletedReconciler{FakeConfigMapReconciler: fakeReconciler, err: errors.New("failed")}
+		reconciler = controller.ConfigMaps(func(namespace, name string) (*apiv1.ConfigMap, error) {
+			return nil, apierrors.NewNotFound(apiv1.Resource("configmaps"), name)
+		}, deleted)
+		_, err := reconciler.Reconcile(context.Background(), "team-a/configmap")
+		Expect(err).To(MatchError("failed"))
+		Expect(deleted.keys).To(Equal([]string{"team-a/configmap"}))
+		Expect(fakeReconciler.ReconcileResourceCallCount()).To(Equal(0))
+	})
+
+	It("requeues configmaps once reconcilers refreshing them are due", func() {
+		refreshing := &refreshingReconciler{FakeConfigMapReconciler: fakeReconciler, after: time.Minute}
+		reconciler = controller.ConfigMaps(func(namespace, name string) (*apiv1.ConfigMap, error) {
+			return configMap, nil
+		}, refreshing)
+		result, err := reconciler.Reconcile(context.Background(), "team-a/configmap")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(controller.Result{RequeueAfter: time.Minute}))
+		Expect(refreshing.configMaps).To(Equal([]*apiv1.ConfigMap{configMap}))
+	})
+
+	It("returns other errors getting the configmap", func() {
+		getErr = errors.New("unavailable")
+		_, err := reconciler.Reconcile(context.Background(), "team-a/configmap")
+		Expect(err).To(MatchError("unavailable"))
+	})
+})
+
+type deletedReconciler struct {
+	*fakes.FakeConfigMapReconciler
+	keys []string
+	err  error
+}
+
+func (d *deletedReconciler) ReconcileDeleted(ctx context.Context, namespace, name string) error {
+	d.keys = append(d.keys, namespace+"/"+name)
+	return d.err
+}
+
+type refreshingReconciler struct {
+	*fakes.FakeConfigMapReconciler
+	configMaps []*apiv1.ConfigMap
+	after      time.Duration
+}
+
+func (r *refreshingReconciler) RefreshAfter(cm *apiv1.ConfigMap) time.Duration {
+	r.configMaps = append(r.configMaps, cm)
+	return r.after
+}
@@ -1,73 +1,238 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aclevername/config-map-controller/log"
 
-	apiv1 "k8s.io/api/core/v1"
-
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 )
 
-type ConfigMapController struct {
+// Controller runs a set of informers whose event handlers queue the
+// namespace/name keys of objects, and reconciles each key with a pool of
+// workers. Failed keys are retried with the queue's backoff up to a limit.
+type Controller struct {
 	queue      workqueue.RateLimitingInterface
-	informer   cache.Controller
+	informers  []cache.Controller
 	reconciler Reconciler
+	metrics    Metrics
+	workers    int
+	maxRetries int
+	timeout    time.Duration
+
+	// lastProgress is the unix nano time a worker last picked up or
+	// finished an item, busy is the number of workers reconciling.
+	lastProgress int64
+	busy         int32
 }
 
 //go:generate counterfeiter -o fakes/fake_queue.go k8s.io/client-go/util/workqueue.RateLimitingInterface
 //go:generate counterfeiter -o fakes/fake_informer.go k8s.io/client-go/tools/cache.Controller
 
 //go:generate counterfeiter -o fakes/fake_reconciler.go . Reconciler
+
+// Reconciler reconciles the object with a namespace/name key. ctx is done
+// once the reconcile times out or the controller stops.
 type Reconciler interface {
-	ReconcileResource(cm *apiv1.ConfigMap) error
+	Reconcile(ctx context.Context, key string) (Result, error)
+}
+
+// Result tells the controller what to do with a key once it has been
+// reconciled.
+type Result struct {
+	// RequeueAfter queues the key again after that long when positive, even
+	// if the reconcile failed.
+	RequeueAfter time.Duration
+}
+
+//go:generate counterfeiter -o fakes/fake_metrics.go . Metrics
+type Metrics interface {
+	ReconcileFinished(result string, duration time.Duration)
 }
 
-func NewConfigMapController(queue workqueue.RateLimitingInterface, informer cache.Controller, reconciler Reconciler) *ConfigMapController {
-	return &ConfigMapController{
-		informer:   informer,
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+
+	DefaultMaxRetries = 5
+	DefaultTimeout    = 2 * time.Minute
+)
+
+func New(queue workqueue.RateLimitingInterface, reconciler Reconciler, metrics Metrics, informers ...cache.Controller) *Controller {
+	return &Controller{
 		queue:      queue,
+		informers:  informers,
 		reconciler: reconciler,
+		metrics:    metrics,
+		workers:    1,
+		maxRetries: DefaultMaxRetries,
+		timeout:    DefaultTimeout,
+	}
+}
+
+// SetWorkers sets how many keys are reconciled at once, one by default. It
+// must be called before Run.
+func (c *Controller) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
 	}
+	c.workers = workers
 }
 
-func (c *ConfigMapController) Run(stopCh chan struct{}) {
+// SetMaxRetries sets how many times a failing key is retried before it is
+// dropped until its object changes again or it is due to be requeued.
+func (c *Controller) SetMaxRetries(maxRetries int) {
+	c.maxRetries = maxRetries
+}
+
+// SetTimeout sets how long a single reconcile may take before its context
+// is cancelled, zero for no limit.
+func (c *Controller) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// EnqueueHandler returns handlers queueing the keys of added and updated
+// objects.
+func EnqueueHandler(queue workqueue.Interface) cache.ResourceEventHandlerFuncs {
+	enqueue := func(obj interface{}) {
+		key, err := cache.MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			log.With(log.Fields{"error": err}).Error("failed to get key of object")
+			return
+		}
+		queue.Add(key)
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(old, new interface{}) {
+			enqueue(new)
+		},
+	}
+}
+
+// Run runs the informers and workers until ctx is done or the queue is shut
+// down. Reconciles still running when ctx is done are cancelled.
+func (c *Controller) Run(ctx context.Context) {
+	c.progress()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	var wg sync.WaitGroup
-	wg.Add(1)
+	for _, informer := range c.informers {
+		wg.Add(1)
+		go func(informer cache.Controller) {
+			informer.Run(ctx.Done())
+			wg.Done()
+		}(informer)
+	}
 
+	wg.Add(1)
 	go func() {
-		c.informer.Run(stopCh)
+		<-ctx.Done()
+		c.queue.ShutDown()
 		wg.Done()
 	}()
 
-	defer c.queue.ShutDown()
-	defer close(stopCh)
-
-	for c.run() {
+	var workers sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for c.run(ctx) {
+			}
+		}()
 	}
+	workers.Wait()
 
 	log.Debug("controller shutting down")
+	cancel()
 	wg.Wait()
 }
 
-func (c *ConfigMapController) run() bool {
-	key, quit := c.queue.Get()
+func (c *Controller) run(ctx context.Context) bool {
+	item, quit := c.queue.Get()
 	if quit {
 		return false
 	}
-	defer c.queue.Done(key)
+	defer c.queue.Done(item)
 
-	val, ok := key.(*apiv1.ConfigMap)
+	c.progress()
+	atomic.AddInt32(&c.busy, 1)
+	defer func() {
+		atomic.AddInt32(&c.busy, -1)
+		c.progress()
+	}()
+
+	key, ok := item.(string)
 	if !ok {
+		c.queue.Forget(item)
 		return true
 	}
 
-	err := c.reconciler.ReconcileResource(val)
+	logger := log.With(log.Fields{"key": key})
+	if namespace, name, err := cache.SplitMetaNamespaceKey(key); err == nil {
+		logger = logger.With(log.Fields{"namespace": namespace, "name": name})
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result, err := c.reconciler.Reconcile(ctx, key)
+	duration := time.Since(start)
 	if err != nil {
-		log.Error("error processing  configmap %s/%s, error: %v", key.(*apiv1.ConfigMap).Namespace, key.(*apiv1.ConfigMap).Name, err)
-		return true
+		c.metrics.ReconcileFinished(ResultError, duration)
+		logger = logger.With(log.Fields{"duration": duration, "error": err})
+		if c.queue.NumRequeues(key) < c.maxRetries {
+			logger.Error("error processing object, retrying")
+			c.queue.AddRateLimited(key)
+			return true
+		}
+		logger.Error("error processing object, giving up after %d retries", c.maxRetries)
+	} else {
+		c.metrics.ReconcileFinished(ResultSuccess, duration)
+		logger.With(log.Fields{"duration": duration}).Debug("processed object")
+	}
+
+	c.queue.Forget(key)
+	if result.RequeueAfter > 0 {
+		c.queue.AddAfter(key, result.RequeueAfter)
 	}
 	return true
 }
+
+func (c *Controller) HasSynced() bool {
+	for _, informer := range c.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// Healthy returns an error when work is pending but no worker has picked up
+// or finished an item within window.
+func (c *Controller) Healthy(window time.Duration) error {
+	if c.queue.Len() == 0 && atomic.LoadInt32(&c.busy) == 0 {
+		return nil
+	}
+
+	since := time.Since(time.Unix(0, atomic.LoadInt64(&c.lastProgress)))
+	if since > window {
+		return fmt.Errorf("no progress processing the queue for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+func (c *Controller) progress() {
+	atomic.StoreInt64(&c.lastProgress, time.Now().UnixNano())
+}
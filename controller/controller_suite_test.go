@@ -9,5 +9,5 @@ import (
 
 func TestController(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "ConfigMapController Suite")
+	RunSpecs(t, "Controller Suite")
 }
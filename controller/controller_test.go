@@ -1,6 +1,10 @@
 package controller_test
 
 import (
+	"context"
+	"errors"
+	"time"
+
 	"github.com/aclevername/config-map-controller/controller"
 	"github.com/aclevername/config-map-controller/controller/fakes"
 	apiv1 "k8s.io/api/core/v1"
@@ -16,13 +20,15 @@ import (
 	. "github.com/onsi/gomega"
 )
 
-var _ = Describe("ConfigMapController", func() {
+var _ = Describe("Controller", func() {
 	var (
-		clientset        *fake.Clientset
-		fakereconcileror *fakes.FakeReconciler
-		queue            workqueue.RateLimitingInterface
-		informer         cache.Controller
-		configMap        *apiv1.ConfigMap
+		clientset      *fake.Clientset
+		fakeReconciler *fakes.FakeReconciler
+		fakeMetrics    *fakes.FakeMetrics
+		queue          workqueue.RateLimitingInterface
+		informer       cache.Controller
+		configMap      *apiv1.ConfigMap
+		key            = "default/configmap"
 	)
 	BeforeEach(func() {
 		configMap = &apiv1.ConfigMap{
@@ -34,7 +40,8 @@ var _ = Describe("ConfigMapController", func() {
 
 		clientset = fake.NewSimpleClientset(configMap)
 
-		fakereconcileror = new(fakes.FakeReconciler)
+		fakeReconciler = new(fakes.FakeReconciler)
+		fakeMetrics = new(fakes.FakeMetrics)
 
 		configMapListWatcher := cache.NewListWatchFromClient(clientset.CoreV1().RESTClient(), "configmaps", v1.NamespaceAll, fields.Everything())
 
@@ -44,12 +51,25 @@ var _ = Describe("ConfigMapController", func() {
 
 	})
 
+	// getOnce stubs the queue to hand out item once and then quit.
+	getOnce := func(item interface{}) func() (interface{}, bool) {
+		var callCount int
+		return func() (i interface{}, b bool) {
+			if callCount == 0 {
+				callCount++
+				return item, false
+			}
+			return nil, true
+		}
+	}
+
 	Describe("New", func() {
-		It("Builds a ConfigMapController", func() {
-			configMapController := controller.NewConfigMapController(queue, informer, fakereconcileror)
-			Expect(configMapController.GetQueue()).To(Equal(queue))
-			Expect(configMapController.GetInformer()).To(Equal(informer))
-			Expect(configMapController.GetReconciler()).To(Equal(fakereconcileror))
+		It("Builds a Controller", func() {
+			c := controller.New(queue, fakeReconciler, fakeMetrics, informer)
+			Expect(c.GetQueue()).To(Equal(queue))
+			Expect(c.GetInformers()).To(Equal([]cache.Controller{informer}))
+			Expect(c.GetReconciler()).To(Equal(fakeReconciler))
+			Expect(c.GetMetrics()).To(Equal(fakeMetrics))
 
 		})
 	})
@@ -58,85 +78,301 @@ var _ = Describe("ConfigMapController", func() {
 		var (
 			fakeQueue    *fakes.FakeRateLimitingInterface
 			fakeInformer *fakes.FakeController
-			configMap    *apiv1.ConfigMap
-			stopCh       chan struct{}
 		)
 
 		BeforeEach(func() {
 			fakeQueue = new(fakes.FakeRateLimitingInterface)
 			fakeInformer = new(fakes.FakeController)
 			fakeQueue.GetReturns(nil, true)
-			configMap = &apiv1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "configmap",
-					Namespace: "default",
-				},
-			}
-			stopCh = make(chan struct{})
 
 		})
 
 		It("processes the item until told to exit by the queue", func() {
-			var callCount int
-			fakeQueue.GetStub = func() (i interface{}, b bool) {
-				if callCount == 0 {
-					callCount++
-					return configMap, false
-				} else {
-					return nil, true
-				}
-			}
-			configMapController := controller.NewConfigMapController(fakeQueue, fakeInformer, fakereconcileror)
-			configMapController.Run(stopCh)
+			fakeQueue.GetStub = getOnce(key)
+			c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, fakeInformer)
+			c.Run(context.Background())
 			By("Starting the informer")
 			Expect(fakeInformer.RunCallCount()).To(Equal(1))
 
 			By("calling the queue")
 			Expect(fakeQueue.GetCallCount()).To(Equal(2))
 			Expect(fakeQueue.DoneCallCount()).To(Equal(1))
-			Expect(fakeQueue.DoneArgsForCall(0)).To(Equal(configMap))
+			Expect(fakeQueue.DoneArgsForCall(0)).To(Equal(key))
 
 			By("processing the item")
-			Expect(fakereconcileror.ReconcileResourceCallCount()).To(Equal(1))
-			Expect(fakereconcileror.ReconcileResourceArgsForCall(0)).To(Equal(configMap))
+			Expect(fakeReconciler.ReconcileCallCount()).To(Equal(1))
+			_, reconciledKey := fakeReconciler.ReconcileArgsForCall(0)
+			Expect(reconciledKey).To(Equal(key))
+
+			By("recording the result")
+			Expect(fakeMetrics.ReconcileFinishedCallCount()).To(Equal(1))
+			result, _ := fakeMetrics.ReconcileFinishedArgsForCall(0)
+			Expect(result).To(Equal(controller.ResultSuccess))
+
+			By("forgetting the key")
+			Expect(fakeQueue.ForgetCallCount()).To(Equal(1))
+			Expect(fakeQueue.AddRateLimitedCallCount()).To(Equal(0))
+			Expect(fakeQueue.AddAfterCallCount()).To(Equal(0))
 
 			By("shuting down the queue")
 			Expect(fakeQueue.ShutDownCallCount()).To(Equal(1))
 
-			By("closing the channel")
-			Expect(stopCh).To(BeClosed())
+			By("stopping the informer")
+			Expect(fakeInformer.RunArgsForCall(0)).To(BeClosed())
+
+		})
 
+		It("runs every informer", func() {
+			otherInformer := new(fakes.FakeController)
+			c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, fakeInformer, otherInformer)
+			c.Run(context.Background())
+			Expect(fakeInformer.RunCallCount()).To(Equal(1))
+			Expect(otherInformer.RunCallCount()).To(Equal(1))
 		})
 
-		When("the resource provided isn't a configmap", func() {
+		When("the item provided isn't a key", func() {
 			It("does not process the item and marks it as done", func() {
-				var callCount int
-				fakeQueue.GetStub = func() (i interface{}, b bool) {
-					if callCount == 0 {
-						callCount++
-						return "not a configmap struct", false
-					} else {
-						return nil, true
-					}
-				}
-				configMapController := controller.NewConfigMapController(fakeQueue, fakeInformer, fakereconcileror)
-				configMapController.Run(stopCh)
+				fakeQueue.GetStub = getOnce(configMap)
+				c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, fakeInformer)
+				c.Run(context.Background())
 				By("Starting the informer")
 				Expect(fakeInformer.RunCallCount()).To(Equal(1))
 
 				By("calling the queue")
 				Expect(fakeQueue.GetCallCount()).To(Equal(2))
 				Expect(fakeQueue.DoneCallCount()).To(Equal(1))
-				Expect(fakeQueue.DoneArgsForCall(0)).To(Equal("not a configmap struct"))
+				Expect(fakeQueue.DoneArgsForCall(0)).To(Equal(configMap))
 
 				By("processing the item")
-				Expect(fakereconcileror.ReconcileResourceCallCount()).To(Equal(0))
+				Expect(fakeReconciler.ReconcileCallCount()).To(Equal(0))
+				Expect(fakeMetrics.ReconcileFinishedCallCount()).To(Equal(0))
 
 				By("shuting down the queue")
 				Expect(fakeQueue.ShutDownCallCount()).To(Equal(1))
 
-				By("closing the channel")
-				Expect(stopCh).To(BeClosed())
+				By("stopping the informer")
+				Expect(fakeInformer.RunArgsForCall(0)).To(BeClosed())
+			})
+		})
+
+		When("reconciling the key fails", func() {
+			BeforeEach(func() {
+				fakeQueue.GetStub = getOnce(key)
+				fakeReconciler.ReconcileReturns(controller.Result{}, errors.New("failed"))
+			})
+
+			It("records the error result and retries the key", func() {
+				c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, fakeInformer)
+				c.Run(context.Background())
+
+				Expect(fakeMetrics.ReconcileFinishedCallCount()).To(Equal(1))
+				result, _ := fakeMetrics.ReconcileFinishedArgsForCall(0)
+				Expect(result).To(Equal(controller.ResultError))
+				Expect(fakeQueue.DoneCallCount()).To(Equal(1))
+				Expect(fakeQueue.AddRateLimitedCallCount()).To(Equal(1))
+				Expect(fakeQueue.AddRateLimitedArgsForCall(0)).To(Equal(key))
+				Expect(fakeQueue.ForgetCallCount()).To(Equal(0))
+			})
+
+			It("gives up once the key has been retried too often", func() {
+				fakeQueue.NumRequeuesReturns(controller.DefaultMaxRetries)
+				c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, fakeInformer)
+				c.Run(context.Background())
+
+				Expect(fakeQueue.AddRateLimitedCallCount()).To(Equal(0))
+				Expect(fakeQueue.ForgetCallCount()).To(Equal(1))
+			})
+
+			It("honours SetMaxRetries", func() {
+				fakeQueue.NumRequeuesReturns(1)
+				c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, fakeInformer)
+				c.SetMaxRetries(1)
+				c.Run(context.Background())
+
+				Expect(fakeQueue.AddRateLimitedCallCount()).To(Equal(0))
+			})
+		})
+
+		When("the reconciler asks to be requeued", func() {
+			It("adds the key again after that long", func() {
+				fakeQueue.GetStub = getOnce(key)
+				fakeReconciler.ReconcileReturns(controller.Result{RequeueAfter: time.Minute}, nil)
+				c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, fakeInformer)
+				c.Run(context.Background())
+
+				Expect(fakeQueue.AddAfterCallCount()).To(Equal(1))
+				item, after := fakeQueue.AddAfterArgsForCall(0)
+				Expect(item).To(Equal(key))
+				Expect(after).To(Equal(time.Minute))
+			})
+		})
+	})
+
+	Describe("Run with a context", func() {
+		var fakeQueue *fakes.FakeRateLimitingInterface
+
+		BeforeEach(func() {
+			fakeQueue = new(fakes.FakeRateLimitingInterface)
+			fakeQueue.GetStub = getOnce(key)
+		})
+
+		It("gives each reconcile a deadline", func() {
+			fakeReconciler.ReconcileStub = func(ctx context.Context, _ string) (controller.Result, error) {
+				<-ctx.Done()
+				return controller.Result{}, ctx.Err()
+			}
+			c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, new(fakes.FakeController))
+			c.SetTimeout(10 * time.Millisecond)
+			c.Run(context.Background())
+
+			result, _ := fakeMetrics.ReconcileFinishedArgsForCall(0)
+			Expect(result).To(Equal(controller.ResultError))
+			Expect(fakeQueue.AddRateLimitedCallCount()).To(Equal(1))
+		})
+
+		It("cancels running reconciles and shuts down the queue once stopped", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			fakeReconciler.ReconcileStub = func(ctx context.Context, _ string) (controller.Result, error) {
+				cancel()
+				<-ctx.Done()
+				return controller.Result{}, ctx.Err()
+			}
+			c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, new(fakes.FakeController))
+			c.SetTimeout(0)
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				c.Run(ctx)
+			}()
+			Eventually(done).Should(BeClosed())
+			Expect(fakeQueue.ShutDownCallCount()).To(BeNumerically(">=", 1))
+		})
+
+		It("still requeues a failed key that asked to be requeued", func() {
+			fakeQueue.NumRequeuesReturns(controller.DefaultMaxRetries)
+			fakeReconciler.ReconcileReturns(controller.Result{RequeueAfter: time.Minute}, errors.New("failed"))
+			c := controller.New(fakeQueue, fakeReconciler, fakeMetrics, new(fakes.FakeController))
+			c.Run(context.Background())
+
+			Expect(fakeQueue.AddAfterCallCount()).To(Equal(1))
+		})
+	})
+
+	Describe("EnqueueHandler", func() {
+		It("queues the keys of added and updated objects", func() {
+			handler := controller.EnqueueHandler(queue)
+			handler.OnAdd(configMap)
+			Expect(queue.Len()).To(Equal(1))
+			item, _ := queue.Get()
+			Expect(item).To(Equal(key))
+			queue.Done(item)
+
+			handler.OnUpdate(configMap, configMap)
+			Expect(queue.Len()).To(Equal(1))
+		})
+	})
+
+	Describe("SetWorkers", func() {
+		It("reconciles that many keys at once", func() {
+			queue.Add("default/a")
+			queue.Add("default/b")
+
+			started := make(chan struct{}, 2)
+			release := make(chan struct{})
+			fakeReconciler.ReconcileStub = func(context.Context, string) (controller.Result, error) {
+				started <- struct{}{}
+				<-release
+				return controller.Result{}, nil
+			}
+
+			c := controller.New(queue, fakeReconciler, fakeMetrics, new(fakes.FakeController))
+			c.SetWorkers(2)
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				c.Run(context.Background())
+			}()
+
+			Eventually(started).Should(HaveLen(2))
+			close(release)
+			queue.ShutDown()
+			Eventually(done).Should(BeClosed())
+			Expect(fakeReconciler.ReconcileCallCount()).To(Equal(2))
+		})
+	})
+
+	Describe("HasSynced", func() {
+		It("reports whether every informer has synced", func() {
+			fakeInformer := new(fakes.FakeController)
+			otherInformer := new(fakes.FakeController)
+			c := controller.New(queue, fakeReconciler, fakeMetrics, fakeInformer, otherInformer)
+			Expect(c.HasSynced()).To(BeFalse())
+
+			fakeInformer.HasSyncedReturns(true)
+			Expect(c.HasSynced()).To(BeFalse())
+
+			otherInformer.HasSyncedReturns(true)
+			Expect(c.HasSynced()).To(BeTrue())
+		})
+	})
+
+	Describe("Healthy", func() {
+		var (
+			fakeQueue *fakes.FakeRateLimitingInterface
+			c         *controller.Controller
+		)
+
+		BeforeEach(func() {
+			fakeQueue = new(fakes.FakeRateLimitingInterface)
+			c = controller.New(fakeQueue, fakeReconciler, fakeMetrics, new(fakes.FakeController))
+		})
+
+		When("the queue is empty", func() {
+			It("is healthy", func() {
+				Expect(c.Healthy(time.Minute)).To(Succeed())
+			})
+		})
+
+		When("items are pending and the worker has never made progress", func() {
+			It("is unhealthy", func() {
+				fakeQueue.LenReturns(1)
+				Expect(c.Healthy(time.Minute)).To(MatchError(ContainSubstring("no progress processing the queue for")))
+			})
+		})
+
+		When("items are pending and the worker recently finished an item", func() {
+			It("is healthy", func() {
+				fakeQueue.GetStub = getOnce(key)
+				c.Run(context.Background())
+
+				fakeQueue.LenReturns(1)
+				Expect(c.Healthy(time.Minute)).To(Succeed())
+			})
+		})
+
+		When("the worker is stuck reconciling an item", func() {
+			It("is unhealthy once the window has passed", func() {
+				release := make(chan struct{})
+				fakeReconciler.ReconcileStub = func(context.Context, string) (controller.Result, error) {
+					<-release
+					return controller.Result{}, nil
+				}
+				fakeQueue.GetStub = getOnce(key)
+				done := make(chan struct{})
+				go func() {
+					c.Run(context.Background())
+					close(done)
+				}()
+
+				Eventually(fakeReconciler.ReconcileCallCount).Should(Equal(1))
+				Expect(c.Healthy(time.Minute)).To(Succeed())
+				Eventually(func() error {
+					return c.Healthy(10 * time.Millisecond)
+				}).Should(HaveOccurred())
+
+				close(release)
+				Eventually(done).Should(BeClosed())
 			})
 		})
 	})
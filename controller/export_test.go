@@ -5,14 +5,18 @@ import (
 	"k8s.io/client-go/util/workqueue"
 )
 
-func (c *ConfigMapController) GetInformer() cache.Controller {
-	return c.informer
+func (c *Controller) GetInformers() []cache.Controller {
+	return c.informers
 }
 
-func (c *ConfigMapController) GetQueue() workqueue.RateLimitingInterface {
+func (c *Controller) GetQueue() workqueue.RateLimitingInterface {
 	return c.queue
 }
 
-func (c *ConfigMapController) GetReconciler() Reconciler {
+func (c *Controller) GetReconciler() Reconciler {
 	return c.reconciler
 }
+
+func (c *Controller) GetMetrics() Metrics {
+	return c.metrics
+}
@@ -0,0 +1,115 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aclevername/config-map-controller/controller"
+	v1 "k8s.io/api/core/v1"
+)
+
+type FakeConfigMapReconciler struct {
+	ReconcileResourceStub        func(context.Context, *v1.ConfigMap) error
+	reconcileResourceMutex       sync.RWMutex
+	reconcileResourceArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+	}
+	reconcileResourceReturns struct {
+		result1 error
+	}
+	reconcileResourceReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeConfigMapReconciler) ReconcileResource(arg1 context.Context, arg2 *v1.ConfigMap) error {
+	fake.reconcileResourceMutex.Lock()
+	ret, specificReturn := fake.reconcileResourceReturnsOnCall[len(fake.reconcileResourceArgsForCall)]
+	fake.reconcileResourceArgsForCall = append(fake.reconcileResourceArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+	}{arg1, arg2})
+	stub := fake.ReconcileResourceStub
+	fakeReturns := fake.reconcileResourceReturns
+	fake.recordInvocation("ReconcileResource", []interface{}{arg1, arg2})
+	fake.reconcileResourceMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeConfigMapReconciler) ReconcileResourceCallCount() int {
+	fake.reconcileResourceMutex.RLock()
+	defer fake.reconcileResourceMutex.RUnlock()
+	return len(fake.reconcileResourceArgsForCall)
+}
+
+func (fake *FakeConfigMapReconciler) ReconcileResourceCalls(stub func(context.Context, *v1.ConfigMap) error) {
+	fake.reconcileResourceMutex.Lock()
+	defer fake.reconcileResourceMutex.Unlock()
+	fake.ReconcileResourceStub = stub
+}
+
+func (fake *FakeConfigMapReconciler) ReconcileResourceArgsForCall(i int) (context.Context, *v1.ConfigMap) {
+	fake.reconcileResourceMutex.RLock()
+	defer fake.reconcileResourceMutex.RUnlock()
+	argsForCall := fake.reconcileResourceArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeConfigMapReconciler) ReconcileResourceReturns(result1 error) {
+	fake.reconcileResourceMutex.Lock()
+	defer fake.reconcileResourceMutex.Unlock()
+	fake.ReconcileResourceStub = nil
+	fake.reconcileResourceReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeConfigMapReconciler) ReconcileResourceReturnsOnCall(i int, result1 error) {
+	fake.reconcileResourceMutex.Lock()
+	defer fake.reconcileResourceMutex.Unlock()
+	fake.ReconcileResourceStub = nil
+	if fake.reconcileResourceReturnsOnCall == nil {
+		fake.reconcileResourceReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.reconcileResourceReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeConfigMapReconciler) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.reconcileResourceMutex.RLock()
+	defer fake.reconcileResourceMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeConfigMapReconciler) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ controller.ConfigMapReconciler = new(FakeConfigMapReconciler)
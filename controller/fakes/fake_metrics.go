@@ -0,0 +1,79 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aclevername/config-map-controller/controller"
+)
+
+type FakeMetrics struct {
+	ReconcileFinishedStub        func(string, time.Duration)
+	reconcileFinishedMutex       sync.RWMutex
+	reconcileFinishedArgsForCall []struct {
+		arg1 string
+		arg2 time.Duration
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeMetrics) ReconcileFinished(arg1 string, arg2 time.Duration) {
+	fake.reconcileFinishedMutex.Lock()
+	fake.reconcileFinishedArgsForCall = append(fake.reconcileFinishedArgsForCall, struct {
+		arg1 string
+		arg2 time.Duration
+	}{arg1, arg2})
+	stub := fake.ReconcileFinishedStub
+	fake.recordInvocation("ReconcileFinished", []interface{}{arg1, arg2})
+	fake.reconcileFinishedMutex.Unlock()
+	if stub != nil {
+		fake.ReconcileFinishedStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeMetrics) ReconcileFinishedCallCount() int {
+	fake.reconcileFinishedMutex.RLock()
+	defer fake.reconcileFinishedMutex.RUnlock()
+	return len(fake.reconcileFinishedArgsForCall)
+}
+
+func (fake *FakeMetrics) ReconcileFinishedCalls(stub func(string, time.Duration)) {
+	fake.reconcileFinishedMutex.Lock()
+	defer fake.reconcileFinishedMutex.Unlock()
+	fake.ReconcileFinishedStub = stub
+}
+
+func (fake *FakeMetrics) ReconcileFinishedArgsForCall(i int) (string, time.Duration) {
+	fake.reconcileFinishedMutex.RLock()
+	defer fake.reconcileFinishedMutex.RUnlock()
+	argsForCall := fake.reconcileFinishedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeMetrics) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.reconcileFinishedMutex.RLock()
+	defer fake.reconcileFinishedMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeMetrics) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ controller.Metrics = new(FakeMetrics)
@@ -2,93 +2,101 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/aclevername/config-map-controller/controller"
-	v1 "k8s.io/api/core/v1"
 )
 
 type FakeReconciler struct {
-	ReconcileResourceStub        func(*v1.ConfigMap) error
-	reconcileResourceMutex       sync.RWMutex
-	reconcileResourceArgsForCall []struct {
-		arg1 *v1.ConfigMap
+	ReconcileStub        func(context.Context, string) (controller.Result, error)
+	reconcileMutex       sync.RWMutex
+	reconcileArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
 	}
-	reconcileResourceReturns struct {
-		result1 error
+	reconcileReturns struct {
+		result1 controller.Result
+		result2 error
 	}
-	reconcileResourceReturnsOnCall map[int]struct {
-		result1 error
+	reconcileReturnsOnCall map[int]struct {
+		result1 controller.Result
+		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeReconciler) ReconcileResource(arg1 *v1.ConfigMap) error {
-	fake.reconcileResourceMutex.Lock()
-	ret, specificReturn := fake.reconcileResourceReturnsOnCall[len(fake.reconcileResourceArgsForCall)]
-	fake.reconcileResourceArgsForCall = append(fake.reconcileResourceArgsForCall, struct {
-		arg1 *v1.ConfigMap
-	}{arg1})
-	fake.recordInvocation("ReconcileResource", []interface{}{arg1})
-	fake.reconcileResourceMutex.Unlock()
-	if fake.ReconcileResourceStub != nil {
-		return fake.ReconcileResourceStub(arg1)
+func (fake *FakeReconciler) Reconcile(arg1 context.Context, arg2 string) (controller.Result, error) {
+	fake.reconcileMutex.Lock()
+	ret, specificReturn := fake.reconcileReturnsOnCall[len(fake.reconcileArgsForCall)]
+	fake.reconcileArgsForCall = append(fake.reconcileArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.ReconcileStub
+	fakeReturns := fake.reconcileReturns
+	fake.recordInvocation("Reconcile", []interface{}{arg1, arg2})
+	fake.reconcileMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.reconcileResourceReturns
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeReconciler) ReconcileResourceCallCount() int {
-	fake.reconcileResourceMutex.RLock()
-	defer fake.reconcileResourceMutex.RUnlock()
-	return len(fake.reconcileResourceArgsForCall)
+func (fake *FakeReconciler) ReconcileCallCount() int {
+	fake.reconcileMutex.RLock()
+	defer fake.reconcileMutex.RUnlock()
+	return len(fake.reconcileArgsForCall)
 }
 
-func (fake *FakeReconciler) ReconcileResourceCalls(stub func(*v1.ConfigMap) error) {
-	fake.reconcileResourceMutex.Lock()
-	defer fake.reconcileResourceMutex.Unlock()
-	fake.ReconcileResourceStub = stub
+func (fake *FakeReconciler) ReconcileCalls(stub func(context.Context, string) (controller.Result, error)) {
+	fake.reconcileMutex.Lock()
+	defer fake.reconcileMutex.Unlock()
+	fake.ReconcileStub = stub
 }
 
-func (fake *FakeReconciler) ReconcileResourceArgsForCall(i int) *v1.ConfigMap {
-	fake.reconcileResourceMutex.RLock()
-	defer fake.reconcileResourceMutex.RUnlock()
-	argsForCall := fake.reconcileResourceArgsForCall[i]
-	return argsForCall.arg1
+func (fake *FakeReconciler) ReconcileArgsForCall(i int) (context.Context, string) {
+	fake.reconcileMutex.RLock()
+	defer fake.reconcileMutex.RUnlock()
+	argsForCall := fake.reconcileArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeReconciler) ReconcileResourceReturns(result1 error) {
-	fake.reconcileResourceMutex.Lock()
-	defer fake.reconcileResourceMutex.Unlock()
-	fake.ReconcileResourceStub = nil
-	fake.reconcileResourceReturns = struct {
-		result1 error
-	}{result1}
+func (fake *FakeReconciler) ReconcileReturns(result1 controller.Result, result2 error) {
+	fake.reconcileMutex.Lock()
+	defer fake.reconcileMutex.Unlock()
+	fake.ReconcileStub = nil
+	fake.reconcileReturns = struct {
+		result1 controller.Result
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeReconciler) ReconcileResourceReturnsOnCall(i int, result1 error) {
-	fake.reconcileResourceMutex.Lock()
-	defer fake.reconcileResourceMutex.Unlock()
-	fake.ReconcileResourceStub = nil
-	if fake.reconcileResourceReturnsOnCall == nil {
-		fake.reconcileResourceReturnsOnCall = make(map[int]struct {
-			result1 error
+func (fake *FakeReconciler) ReconcileReturnsOnCall(i int, result1 controller.Result, result2 error) {
+	fake.reconcileMutex.Lock()
+	defer fake.reconcileMutex.Unlock()
+	fake.ReconcileStub = nil
+	if fake.reconcileReturnsOnCall == nil {
+		fake.reconcileReturnsOnCall = make(map[int]struct {
+			result1 controller.Result
+			result2 error
 		})
 	}
-	fake.reconcileResourceReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+	fake.reconcileReturnsOnCall[i] = struct {
+		result1 controller.Result
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeReconciler) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.reconcileResourceMutex.RLock()
-	defer fake.reconcileResourceMutex.RUnlock()
+	fake.reconcileMutex.RLock()
+	defer fake.reconcileMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
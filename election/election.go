@@ -0,0 +1,124 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/aclevername/config-map-controller/log"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// ErrLeaseLost is returned by Run when the lease was lost rather than ctx
+// cancelled.
+var ErrLeaseLost = errors.New("lost the lease")
+
+type Config struct {
+	LeaseName      string
+	LeaseNamespace string
+	Identity       string
+	LeaseDuration  time.Duration
+	RenewDeadline  time.Duration
+	RetryPeriod    time.Duration
+}
+
+type Elector struct {
+	config  Config
+	elector *leaderelection.LeaderElector
+	leading chan context.Context
+	leader  int32
+}
+
+func New(clientset kubernetes.Interface, config Config) (*Elector, error) {
+	e := &Elector{
+		config:  config,
+		leading: make(chan context.Context, 1),
+	}
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Name:      config.LeaseName,
+			Namespace: config.LeaseNamespace,
+		},
+		Client: clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: config.Identity,
+		},
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            config.LeaseName,
+		LeaseDuration:   config.LeaseDuration,
+		RenewDeadline:   config.RenewDeadline,
+		RetryPeriod:     config.RetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: e.onStartedLeading,
+			OnStoppedLeading: e.onStoppedLeading,
+			OnNewLeader:      e.onNewLeader,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.elector = elector
+
+	return e, nil
+}
+
+// Run blocks until ctx is cancelled or the lease is lost, returning
+// ErrLeaseLost in the latter case. run is only called while this instance
+// holds the lease and Run waits for it to return.
+func (e *Elector) Run(parent context.Context, run func(ctx context.Context)) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		e.elector.Run(ctx)
+		close(stopped)
+	}()
+
+	log.Info("waiting to acquire lease %s/%s as %s", e.config.LeaseNamespace, e.config.LeaseName, e.config.Identity)
+
+	select {
+	case leaderCtx := <-e.leading:
+		run(leaderCtx)
+		cancel()
+		<-stopped
+	case <-stopped:
+	}
+
+	if parent.Err() == nil {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (e *Elector) IsLeader() bool {
+	return atomic.LoadInt32(&e.leader) == 1
+}
+
+func (e *Elector) onStartedLeading(ctx context.Context) {
+	atomic.StoreInt32(&e.leader, 1)
+	log.Info("acquired lease %s/%s", e.config.LeaseNamespace, e.config.LeaseName)
+	e.leading <- ctx
+}
+
+func (e *Elector) onStoppedLeading() {
+	if atomic.SwapInt32(&e.leader, 0) == 1 {
+		log.Info("lost lease %s/%s", e.config.LeaseNamespace, e.config.LeaseName)
+	}
+}
+
+func (e *Elector) onNewLeader(identity string) {
+	if identity != e.config.Identity {
+		log.Info("%s is the current leader, not reconciling", identity)
+	}
+}
@@ -0,0 +1,153 @@
+package election_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/aclevername/config-map-controller/election"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Elector", func() {
+	var (
+		fakeClient *fake.Clientset
+		config     election.Config
+		namespace  = "my-namespace"
+		leaseName  = "my-lease"
+	)
+
+	BeforeEach(func() {
+		fakeClient = fake.NewSimpleClientset()
+		config = election.Config{
+			LeaseName:      leaseName,
+			LeaseNamespace: namespace,
+			Identity:       "me",
+			LeaseDuration:  time.Second,
+			RenewDeadline:  500 * time.Millisecond,
+			RetryPeriod:    100 * time.Millisecond,
+		}
+	})
+
+	Describe("New", func() {
+		When("the renew deadline is longer than the lease duration", func() {
+			BeforeEach(func() {
+				config.RenewDeadline = 2 * time.Second
+			})
+
+			It("returns an error", func() {
+				_, err := election.New(fakeClient, config)
+				Expect(err).To(MatchError("leaseDuration must be greater than renewDeadline"))
+			})
+		})
+	})
+
+	Describe("Run", func() {
+		var (
+			elector *election.Elector
+			ctx     context.Context
+			cancel  context.CancelFunc
+			started chan context.Context
+			done    chan struct{}
+			runErr  error
+		)
+
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+			started = make(chan context.Context, 1)
+			done = make(chan struct{})
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			elector, err = election.New(fakeClient, config)
+			Expect(err).NotTo(HaveOccurred())
+
+			go func() {
+				runErr = elector.Run(ctx, func(leaderCtx context.Context) {
+					started <- leaderCtx
+					<-leaderCtx.Done()
+				})
+				close(done)
+			}()
+		})
+
+		AfterEach(func() {
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+
+		When("no one holds the lease", func() {
+			It("acquires the lease and runs until cancelled", func() {
+				var leaderCtx context.Context
+				Eventually(started).Should(Receive(&leaderCtx))
+				Expect(elector.IsLeader()).To(BeTrue())
+
+				By("recording itself as the holder")
+				lease, err := fakeClient.CoordinationV1().Leases(namespace).Get(leaseName, metav1.GetOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*lease.Spec.HolderIdentity).To(Equal("me"))
+
+				By("stopping the work and returning when cancelled")
+				cancel()
+				Eventually(leaderCtx.Done()).Should(BeClosed())
+				Eventually(done).Should(BeClosed())
+				Expect(elector.IsLeader()).To(BeFalse())
+				Expect(runErr).NotTo(HaveOccurred())
+			})
+
+			It("returns an error once the lease is lost", func() {
+				Eventually(started).Should(Receive())
+
+				By("another instance taking the lease over")
+				lease, err := fakeClient.CoordinationV1().Leases(namespace).Get(leaseName, metav1.GetOptions{})
+				Expect(err).NotTo(HaveOccurred())
+				holder := "someone-else"
+				durationSeconds := int32(60)
+				now := metav1.NewMicroTime(time.Now())
+				lease.Spec.HolderIdentity = &holder
+				lease.Spec.LeaseDurationSeconds = &durationSeconds
+				lease.Spec.RenewTime = &now
+				_, err = fakeClient.CoordinationV1().Leases(namespace).Update(lease)
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(done, 5*time.Second).Should(BeClosed())
+				Expect(runErr).To(Equal(election.ErrLeaseLost))
+				Expect(elector.IsLeader()).To(BeFalse())
+			})
+		})
+
+		When("another instance holds the lease", func() {
+			BeforeEach(func() {
+				holder := "someone-else"
+				durationSeconds := int32(60)
+				now := metav1.NewMicroTime(time.Now())
+				_, err := fakeClient.CoordinationV1().Leases(namespace).Create(&coordinationv1.Lease{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      leaseName,
+						Namespace: namespace,
+					},
+					Spec: coordinationv1.LeaseSpec{
+						HolderIdentity:       &holder,
+						LeaseDurationSeconds: &durationSeconds,
+						AcquireTime:          &now,
+						RenewTime:            &now,
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("does not run and is not the leader", func() {
+				Consistently(started, 500*time.Millisecond).ShouldNot(Receive())
+				Expect(elector.IsLeader()).To(BeFalse())
+
+				cancel()
+				Eventually(done).Should(BeClosed())
+			})
+		})
+	})
+})
@@ -0,0 +1,34 @@
+#!/usr/bin/env bash
+
+# Regenerates the deepcopy functions, clientset, listers and informers for the
+# types in apis/. Needs deepcopy-gen, client-gen, lister-gen and informer-gen
+# from k8s.io/code-generator v0.17.0 on the PATH. The generators only work in
+# GOPATH mode, so the module is linked into a temporary GOPATH with its
+# dependencies vendored.
+
+set -euo pipefail
+
+root=$(cd "$(dirname "$0")/.." && pwd)
+pkg=github.com/aclevername/config-map-controller
+header=hack/boilerplate.go.txt
+
+gopath=$(mktemp -d)
+trap 'rm -rf "$gopath" "$root/vendor"' EXIT
+mkdir -p "$gopath/src/$(dirname $pkg)"
+ln -s "$root" "$gopath/src/$pkg"
+
+cd "$root"
+go mod vendor
+cd "$gopath/src/$pkg"
+
+export GOPATH=$gopath GO111MODULE=off
+
+deepcopy-gen --input-dirs $pkg/apis/curlme/v1alpha1 -O zz_generated.deepcopy \
+  --bounding-dirs $pkg/apis --go-header-file $header
+client-gen --clientset-name versioned --input-base "" --input $pkg/apis/curlme/v1alpha1 \
+  --output-package $pkg/client/clientset --output-base "$gopath/src" --go-header-file $header
+lister-gen --input-dirs $pkg/apis/curlme/v1alpha1 \
+  --output-package $pkg/client/listers --output-base "$gopath/src" --go-header-file $header
+informer-gen --input-dirs $pkg/apis/curlme/v1alpha1 \
+  --versioned-clientset-package $pkg/client/clientset/versioned --listers-package $pkg/client/listers \
+  --output-package $pkg/client/informers --output-base "$gopath/src" --go-header-file $header
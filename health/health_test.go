@@ -0,0 +1,93 @@
+package health_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/aclevername/config-map-controller/health"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checker", func() {
+	var (
+		checker *health.Checker
+		server  *httptest.Server
+	)
+
+	BeforeEach(func() {
+		checker = health.New()
+		server = httptest.NewServer(checker.Handler())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	get := func(path string) (int, string) {
+		resp, err := server.Client().Get(server.URL + path)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp.StatusCode, string(body)
+	}
+
+	When("there are no checks", func() {
+		It("reports healthy and ready", func() {
+			code, body := get("/healthz")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(Equal("ok\n"))
+
+			code, _ = get("/readyz")
+			Expect(code).To(Equal(http.StatusOK))
+		})
+	})
+
+	When("all checks pass", func() {
+		BeforeEach(func() {
+			checker.AddLivenessCheck("worker", func() error { return nil })
+			checker.AddReadinessCheck("informer", func() error { return nil })
+		})
+
+		It("reports healthy and ready", func() {
+			code, _ := get("/healthz")
+			Expect(code).To(Equal(http.StatusOK))
+
+			code, _ = get("/readyz")
+			Expect(code).To(Equal(http.StatusOK))
+		})
+	})
+
+	When("a readiness check fails", func() {
+		BeforeEach(func() {
+			checker.AddLivenessCheck("worker", func() error { return nil })
+			checker.AddReadinessCheck("informer", func() error { return nil })
+			checker.AddReadinessCheck("leader-election", func() error { return errors.New("not the leader") })
+		})
+
+		It("reports not ready with the failing check but stays healthy", func() {
+			code, body := get("/readyz")
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(body).To(Equal("leader-election: not the leader\n"))
+
+			code, _ = get("/healthz")
+			Expect(code).To(Equal(http.StatusOK))
+		})
+	})
+
+	When("a liveness check fails", func() {
+		BeforeEach(func() {
+			checker.AddLivenessCheck("worker", func() error { return errors.New("stuck") })
+		})
+
+		It("reports unhealthy", func() {
+			code, body := get("/healthz")
+			Expect(code).To(Equal(http.StatusServiceUnavailable))
+			Expect(body).To(Equal("worker: stuck\n"))
+		})
+	})
+})
@@ -0,0 +1,250 @@
+package history
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// RollbackAnnotation set to a revision number on a ConfigMap restores the
+	// revision and stops fetching until it is removed.
+	RollbackAnnotation = "x-k8s.io/rollback-to"
+
+	// IndexKey is the data key of the companion ConfigMap listing the
+	// revisions it holds.
+	IndexKey = "history.json"
+
+	// MaxBytes keeps the companion ConfigMap below the 1MiB limit of the API
+	// server, leaving room for its metadata.
+	MaxBytes = 1024*1024 - 16*1024
+
+	suffix = "-history"
+)
+
+// Revision describes a fetched version of a data key. Its content is stored
+// under the data key <key>.<revision> of the companion ConfigMap.
+type Revision struct {
+	Key       string      `json:"key"`
+	Revision  int         `json:"revision"`
+	FetchTime metav1.Time `json:"fetchTime"`
+	SHA256    string      `json:"sha256"`
+	Size      int         `json:"size"`
+}
+
+// Store keeps the last revisions of the data keys of each ConfigMap in a
+// companion ConfigMap owned by it.
+type Store struct {
+	clientset kubernetes.Interface
+	revisions int
+	now       func() time.Time
+}
+
+// New returns a Store keeping up to revisions revisions of each data key.
+func New(clientset kubernetes.Interface, revisions int) *Store {
+	return &Store{
+		clientset: clientset,
+		revisions: revisions,
+		now:       time.Now,
+	}
+}
+
+// Name returns the name of the companion ConfigMap of the ConfigMap name.
+func Name(name string) string {
+	if len(name)+len(suffix) > 253 {
+		sum := sha256.Sum256([]byte(name))
+		name = name[:253-len(suffix)-17] + "-" + hex.EncodeToString(sum[:])[:16]
+	}
+	return name + suffix
+}
+
+// Record adds value as the latest revision of key unless it already is,
+// pruning the oldest revisions beyond the limit or MaxBytes.
+func (s *Store) Record(ctx context.Context, configMap *apiv1.ConfigMap, key, value string) error {
+	return s.record(ctx, configMap, key, value, false)
+}
+
+// Backfill is Record for data that was set without being recorded, such as
+// data added by the prefetch webhook. Nothing is added if any revision of
+// key holds value, so data restored by a rollback isn't recorded again.
+func (s *Store) Backfill(ctx context.Context, configMap *apiv1.ConfigMap, key, value string) error {
+	return s.record(ctx, configMap, key, value, true)
+}
+
+func (s *Store) record(ctx context.Context, configMap *apiv1.ConfigMap, key, value string, anyRevision bool) error {
+	companion, revisions, err := s.get(configMap)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	checksum := hex.EncodeToString(sum[:])
+	var latest Revision
+	for _, revision := range revisions {
+		if revision.Key != key {
+			continue
+		}
+		if anyRevision && revision.SHA256 == checksum {
+			return nil
+		}
+		if revision.Revision > latest.Revision {
+			latest = revision
+		}
+	}
+	if latest.SHA256 == checksum {
+		return nil
+	}
+
+	revision := Revision{
+		Key:       key,
+		Revision:  latest.Revision + 1,
+		FetchTime: metav1.NewTime(s.now()),
+		SHA256:    checksum,
+		Size:      len(value),
+	}
+	revisions = append(revisions, revision)
+
+	data := map[string]string{}
+	if companion != nil {
+		for k, v := range companion.Data {
+			data[k] = v
+		}
+	}
+	data[dataKey(key, revision.Revision)] = value
+
+	revisions, err = s.prune(revisions, data)
+	if err != nil {
+		return err
+	}
+	index, err := json.Marshal(revisions)
+	if err != nil {
+		return err
+	}
+	data[IndexKey] = string(index)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if companion == nil {
+		companion = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      Name(configMap.Name),
+				Namespace: configMap.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					*metav1.NewControllerRef(configMap, apiv1.SchemeGroupVersion.WithKind("ConfigMap")),
+				},
+			},
+			Data: data,
+		}
+		_, err = s.clientset.CoreV1().ConfigMaps(configMap.Namespace).Create(companion)
+	} else {
+		companion = companion.DeepCopy()
+		companion.Data = data
+		_, err = s.clientset.CoreV1().ConfigMaps(configMap.Namespace).Update(companion)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write history: %v", err)
+	}
+	return nil
+}
+
+// prune drops the oldest revisions of each key beyond the limit, then the
+// oldest revisions overall until data fits in MaxBytes. The newest revision
+// is never dropped, so it is an error if it doesn't fit on its own.
+func (s *Store) prune(revisions []Revision, data map[string]string) ([]Revision, error) {
+	counts := map[string]int{}
+	for _, revision := range revisions {
+		counts[revision.Key]++
+	}
+
+	var kept []Revision
+	for _, revision := range revisions {
+		if counts[revision.Key] > s.revisions {
+			counts[revision.Key]--
+			delete(data, dataKey(revision.Key, revision.Revision))
+			continue
+		}
+		kept = append(kept, revision)
+	}
+
+	// revisions are appended in order, so the oldest come first.
+	for size(kept, data) > MaxBytes {
+		if len(kept) == 1 {
+			return nil, fmt.Errorf("revision %d of %s is too large to keep in the history, %d bytes", kept[0].Revision, kept[0].Key, kept[0].Size)
+		}
+		delete(data, dataKey(kept[0].Key, kept[0].Revision))
+		kept = kept[1:]
+	}
+	return kept, nil
+}
+
+func size(revisions []Revision, data map[string]string) int {
+	index, _ := json.Marshal(revisions)
+	total := len(IndexKey) + len(index)
+	for k, v := range data {
+		if k != IndexKey {
+			total += len(k) + len(v)
+		}
+	}
+	return total
+}
+
+// Revision returns the content of revision of key.
+func (s *Store) Revision(ctx context.Context, configMap *apiv1.ConfigMap, key string, revision int) (string, error) {
+	companion, revisions, err := s.get(configMap)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range revisions {
+		if r.Key == key && r.Revision == revision {
+			value, ok := companion.Data[dataKey(key, revision)]
+			if ok {
+				return value, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no revision %d of %s in the history", revision, key)
+}
+
+func (s *Store) get(configMap *apiv1.ConfigMap) (*apiv1.ConfigMap, []Revision, error) {
+	companion, err := s.clientset.CoreV1().ConfigMaps(configMap.Namespace).Get(Name(configMap.Name), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get history: %v", err)
+	}
+	if !metav1.IsControlledBy(companion, configMap) {
+		return nil, nil, fmt.Errorf("configmap %s already exists and is not owned by %s", companion.Name, configMap.Name)
+	}
+
+	var revisions []Revision
+	if index, ok := companion.Data[IndexKey]; ok {
+		if err := json.Unmarshal([]byte(index), &revisions); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode history: %v", err)
+		}
+	}
+	return companion, revisions, nil
+}
+
+// ParseRollback returns the revision a rollback annotation value points at.
+func ParseRollback(value string) (int, error) {
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, fmt.Errorf("invalid %s annotation '%s', expected a revision number", RollbackAnnotation, value)
+	}
+	return revision, nil
+}
+
+func dataKey(key string, revision int) string {
+	return fmt.Sprintf("%s.%d", key, revision)
+}
@@ -0,0 +1,126 @@
+package history_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/history"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Store", func() {
+	var (
+		clientset *fake.Clientset
+		configMap *apiv1.ConfigMap
+		store     *history.Store
+		ctx       = context.Background()
+	)
+
+	BeforeEach(func() {
+		configMap = &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "jokes", UID: "jokes-id"}}
+		clientset = fake.NewSimpleClientset(configMap)
+		store = history.New(clientset, 2)
+	})
+
+	getCompanion := func() *apiv1.ConfigMap {
+		companion, err := clientset.CoreV1().ConfigMaps("team-a").Get("jokes-history", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return companion
+	}
+
+	revisions := func() []history.Revision {
+		var revisions []history.Revision
+		Expect(json.Unmarshal([]byte(getCompanion().Data[history.IndexKey]), &revisions)).To(Succeed())
+		return revisions
+	}
+
+	It("keeps revisions in a companion configmap owned by the original", func() {
+		Expect(store.Record(ctx, configMap, "joke", "first")).To(Succeed())
+		Expect(store.Record(ctx, configMap, "joke", "second")).To(Succeed())
+
+		companion := getCompanion()
+		Expect(metav1.IsControlledBy(companion, configMap)).To(BeTrue())
+		Expect(companion.Data).To(HaveKeyWithValue("joke.1", "first"))
+		Expect(companion.Data).To(HaveKeyWithValue("joke.2", "second"))
+
+		Expect(revisions()).To(HaveLen(2))
+		revision := revisions()[1]
+		Expect(revision.Key).To(Equal("joke"))
+		Expect(revision.Revision).To(Equal(2))
+		Expect(revision.Size).To(Equal(6))
+		Expect(revision.SHA256).To(Equal("16367aacb67a4a017c8da8ab95682ccb390863780f7114dda0a0e0c55644c7c4"))
+		Expect(revision.FetchTime.IsZero()).To(BeFalse())
+
+		value, err := store.Revision(ctx, configMap, "joke", 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal("first"))
+	})
+
+	It("doesn't record the latest revision again", func() {
+		Expect(store.Record(ctx, configMap, "joke", "first")).To(Succeed())
+		Expect(store.Record(ctx, configMap, "joke", "first")).To(Succeed())
+		Expect(revisions()).To(HaveLen(1))
+	})
+
+	It("backfills values no revision holds", func() {
+		Expect(store.Backfill(ctx, configMap, "joke", "first")).To(Succeed())
+		Expect(store.Record(ctx, configMap, "joke", "second")).To(Succeed())
+
+		By("skipping values an older revision holds, as restored by a rollback")
+		Expect(store.Backfill(ctx, configMap, "joke", "first")).To(Succeed())
+		Expect(revisions()).To(HaveLen(2))
+
+		Expect(store.Backfill(ctx, configMap, "joke", "third")).To(Succeed())
+		Expect(revisions()).To(HaveLen(2))
+		Expect(getCompanion().Data).To(HaveKeyWithValue("joke.3", "third"))
+	})
+
+	It("prunes revisions beyond the limit", func() {
+		for _, value := range []string{"first", "second", "third"} {
+			Expect(store.Record(ctx, configMap, "joke", value)).To(Succeed())
+		}
+
+		Expect(getCompanion().Data).NotTo(HaveKey("joke.1"))
+		Expect(revisions()).To(HaveLen(2))
+		Expect(revisions()[0].Revision).To(Equal(2))
+
+		_, err := store.Revision(ctx, configMap, "joke", 1)
+		Expect(err).To(MatchError("no revision 1 of joke in the history"))
+	})
+
+	It("prunes the oldest revisions to stay within the size limit", func() {
+		large := strings.Repeat("a", history.MaxBytes/2)
+		Expect(store.Record(ctx, configMap, "joke", large)).To(Succeed())
+		Expect(store.Record(ctx, configMap, "joke", large+"b")).To(Succeed())
+
+		Expect(revisions()).To(HaveLen(1))
+		Expect(revisions()[0].Revision).To(Equal(2))
+	})
+
+	It("refuses revisions too large to keep", func() {
+		err := store.Record(ctx, configMap, "joke", strings.Repeat("a", history.MaxBytes))
+		Expect(err).To(MatchError(ContainSubstring("revision 1 of joke is too large to keep in the history")))
+	})
+
+	It("leaves configmaps it doesn't own alone", func() {
+		_, err := clientset.CoreV1().ConfigMaps("team-a").Create(&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "jokes-history"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		err = store.Record(ctx, configMap, "joke", "first")
+		Expect(err).To(MatchError("configmap jokes-history already exists and is not owned by jokes"))
+	})
+})
+
+var _ = Describe("Name", func() {
+	It("keeps long names valid", func() {
+		Expect(history.Name("jokes")).To(Equal("jokes-history"))
+		Expect(len(history.Name(strings.Repeat("a", 253)))).To(Equal(253))
+	})
+})
@@ -0,0 +1,181 @@
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+//go:generate counterfeiter -o fakes/fake_validator.go . Validator
+
+type Validator interface {
+	Validate(configMap *apiv1.ConfigMap) error
+}
+
+// Result is the outcome of checking a single ConfigMap, or of reading a file
+// or document that could not be checked.
+type Result struct {
+	File      string `json:"file"`
+	Document  int    `json:"document,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (r Result) Failed() bool {
+	return r.Error != ""
+}
+
+// Position describes where the result came from, e.g.
+// fixtures/config-map-invalid.yml:1 (document 1).
+func (r Result) Position() string {
+	if r.Line == 0 {
+		return r.File
+	}
+	return fmt.Sprintf("%s:%d (document %d)", r.File, r.Line, r.Document)
+}
+
+// Object describes the ConfigMap the result is for, e.g. ConfigMap
+// default/invalid.
+func (r Result) Object() string {
+	if r.Name == "" {
+		return ""
+	}
+	if r.Namespace == "" {
+		return "ConfigMap " + r.Name
+	}
+	return fmt.Sprintf("ConfigMap %s/%s", r.Namespace, r.Name)
+}
+
+// Paths checks every ConfigMap in the yaml and json files at paths,
+// descending into directories. Documents of other kinds are skipped.
+func Paths(paths []string, validator Validator) []Result {
+	var results []Result
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				results = append(results, Result{File: file, Error: err.Error()})
+				return nil
+			}
+			if info.IsDir() || (file != path && !isManifest(file)) {
+				return nil
+			}
+			results = append(results, File(file, validator)...)
+			return nil
+		})
+		if err != nil {
+			results = append(results, Result{File: path, Error: err.Error()})
+		}
+	}
+	return results
+}
+
+func File(file string, validator Validator) []Result {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return []Result{{File: file, Error: err.Error()}}
+	}
+
+	var results []Result
+	for i, doc := range splitDocuments(data) {
+		result := Result{File: file, Document: i + 1, Line: doc.line}
+
+		var typeMeta metav1.TypeMeta
+		if err := yaml.Unmarshal(doc.content, &typeMeta); err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		if typeMeta.APIVersion != "v1" || typeMeta.Kind != "ConfigMap" {
+			continue
+		}
+
+		var configMap apiv1.ConfigMap
+		if err := yaml.UnmarshalStrict(doc.content, &configMap); err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Namespace = configMap.Namespace
+		result.Name = configMap.Name
+		if err := validator.Validate(&configMap); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func isManifest(file string) bool {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+type document struct {
+	line    int
+	content []byte
+}
+
+// splitDocuments splits a yaml stream on --- separators, dropping documents
+// that only hold comments. JSON is a single document.
+func splitDocuments(data []byte) []document {
+	if json.Valid(data) {
+		return []document{{line: firstContentLine(data, 1), content: data}}
+	}
+
+	var (
+		documents []document
+		current   bytes.Buffer
+		start     = 1
+		line      = 0
+	)
+	flush := func() {
+		content := append([]byte{}, current.Bytes()...)
+		if first := firstContentLine(content, start); first > 0 {
+			documents = append(documents, document{line: first, content: content})
+		}
+		current.Reset()
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "---" || strings.HasPrefix(text, "--- ") || strings.HasPrefix(text, "---\t") {
+			flush()
+			start = line + 1
+			continue
+		}
+		current.WriteString(text)
+		current.WriteByte('\n')
+	}
+	flush()
+	return documents
+}
+
+// firstContentLine returns the line number of the first line of content that
+// isn't blank or a comment, counting from start, or 0 if there is none.
+func firstContentLine(content []byte, start int) int {
+	for i, text := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimSpace(text)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return start + i
+		}
+	}
+	return 0
+}
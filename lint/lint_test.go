@@ -0,0 +1,126 @@
+package lint_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/lint"
+	"github.com/aclevername/config-map-controller/lint/fakes"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Paths", func() {
+	var (
+		dir           string
+		fakeValidator *fakes.FakeValidator
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "lint")
+		Expect(err).NotTo(HaveOccurred())
+
+		fakeValidator = new(fakes.FakeValidator)
+		fakeValidator.ValidateStub = func(configMap *apiv1.ConfigMap) error {
+			if configMap.Name == "invalid" {
+				return errors.New("bad annotation")
+			}
+			return nil
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	It("checks every configmap in a multi document yaml file, reporting its position", func() {
+		file := writeFile("configmaps.yml", `# leading comment
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: valid
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: skipped
+---
+
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: invalid
+  namespace: team-a
+`)
+
+		results := lint.Paths([]string{file}, fakeValidator)
+		Expect(results).To(Equal([]lint.Result{
+			{File: file, Document: 1, Line: 3, Name: "valid"},
+			{File: file, Document: 3, Line: 14, Namespace: "team-a", Name: "invalid", Error: "bad annotation"},
+		}))
+		Expect(fakeValidator.ValidateCallCount()).To(Equal(2))
+	})
+
+	It("checks json files", func() {
+		file := writeFile("configmap.json", `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "invalid"}}`)
+
+		results := lint.Paths([]string{file}, fakeValidator)
+		Expect(results).To(Equal([]lint.Result{
+			{File: file, Document: 1, Line: 1, Name: "invalid", Error: "bad annotation"},
+		}))
+	})
+
+	It("descends into directories, only reading manifests", func() {
+		valid := writeFile("a/valid.yaml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: valid\n")
+		invalid := writeFile("b/c/invalid.yml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: invalid\n")
+		writeFile("b/README.md", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: invalid\n")
+
+		results := lint.Paths([]string{dir}, fakeValidator)
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].File).To(Equal(valid))
+		Expect(results[1].File).To(Equal(invalid))
+		Expect(results[1].Failed()).To(BeTrue())
+	})
+
+	It("reports documents that don't decode", func() {
+		file := writeFile("broken.yml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: broken\ndata:\n- port\n---\nkind: [\n")
+
+		results := lint.Paths([]string{file}, fakeValidator)
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].Line).To(Equal(1))
+		Expect(results[0].Error).To(ContainSubstring("cannot unmarshal array"))
+		Expect(results[1].Document).To(Equal(2))
+		Expect(results[1].Line).To(Equal(8))
+		Expect(results[1].Error).NotTo(BeEmpty())
+		Expect(fakeValidator.ValidateCallCount()).To(Equal(0))
+	})
+
+	It("reports unknown fields", func() {
+		file := writeFile("typo.yml", "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: typo\n  anotations:\n    x-k8s.io/curl-me-that: mydata=example.com\n")
+
+		results := lint.Paths([]string{file}, fakeValidator)
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Error).To(ContainSubstring(`unknown field "anotations"`))
+	})
+
+	It("reports paths that can't be read", func() {
+		results := lint.Paths([]string{filepath.Join(dir, "missing.yml")}, fakeValidator)
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Error).To(ContainSubstring("no such file or directory"))
+		Expect(results[0].Position()).To(Equal(filepath.Join(dir, "missing.yml")))
+	})
+})
@@ -0,0 +1,137 @@
+package lint
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+func Failures(results []Result) int {
+	failures := 0
+	for _, result := range results {
+		if result.Failed() {
+			failures++
+		}
+	}
+	return failures
+}
+
+// Write reports results in format, one of FormatText, FormatJSON or
+// FormatJUnit.
+func Write(w io.Writer, format string, results []Result) error {
+	switch format {
+	case FormatText:
+		return writeText(w, results)
+	case FormatJSON:
+		return writeJSON(w, results)
+	case FormatJUnit:
+		return writeJUnit(w, results)
+	}
+	return fmt.Errorf("unknown format '%s', expected %s, %s or %s", format, FormatText, FormatJSON, FormatJUnit)
+}
+
+func writeText(w io.Writer, results []Result) error {
+	for _, result := range results {
+		if !result.Failed() {
+			continue
+		}
+		message := result.Error
+		if object := result.Object(); object != "" {
+			message = object + ": " + message
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", result.Position(), message); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d checked, %d problems\n", len(results), Failures(results))
+	return err
+}
+
+type jsonReport struct {
+	Checked  int      `json:"checked"`
+	Problems int      `json:"problems"`
+	Results  []Result `json:"results"`
+}
+
+func writeJSON(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(jsonReport{
+		Checked:  len(results),
+		Problems: Failures(results),
+		Results:  results,
+	})
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func writeJUnit(w io.Writer, results []Result) error {
+	suite := junitTestSuite{
+		Name:     "config-map-controller validate",
+		Tests:    len(results),
+		Failures: Failures(results),
+	}
+	for _, result := range results {
+		name := result.Object()
+		if name == "" {
+			name = result.Position()
+		}
+		testCase := junitTestCase{
+			Name:      name,
+			ClassName: result.File,
+			File:      result.File,
+			Line:      result.Line,
+		}
+		if result.Failed() {
+			testCase.Failure = &junitFailure{
+				Message: result.Error,
+				Text:    result.Position() + ": " + result.Error,
+			}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
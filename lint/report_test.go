@@ -0,0 +1,83 @@
+package lint_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/lint"
+)
+
+var _ = Describe("Write", func() {
+	var (
+		buffer  *bytes.Buffer
+		results []lint.Result
+	)
+
+	BeforeEach(func() {
+		buffer = new(bytes.Buffer)
+		results = []lint.Result{
+			{File: "configmaps.yml", Document: 1, Line: 1, Name: "valid"},
+			{File: "configmaps.yml", Document: 2, Line: 9, Namespace: "team-a", Name: "invalid", Error: "bad annotation"},
+			{File: "missing.yml", Error: "no such file or directory"},
+		}
+	})
+
+	It("writes problems as text", func() {
+		Expect(lint.Write(buffer, lint.FormatText, results)).To(Succeed())
+		Expect(buffer.String()).To(Equal(`configmaps.yml:9 (document 2): ConfigMap team-a/invalid: bad annotation
+missing.yml: no such file or directory
+3 checked, 2 problems
+`))
+	})
+
+	It("writes every result as json", func() {
+		Expect(lint.Write(buffer, lint.FormatJSON, results)).To(Succeed())
+
+		var report struct {
+			Checked  int           `json:"checked"`
+			Problems int           `json:"problems"`
+			Results  []lint.Result `json:"results"`
+		}
+		Expect(json.Unmarshal(buffer.Bytes(), &report)).To(Succeed())
+		Expect(report.Checked).To(Equal(3))
+		Expect(report.Problems).To(Equal(2))
+		Expect(report.Results).To(Equal(results))
+	})
+
+	It("writes every result as a junit test case", func() {
+		Expect(lint.Write(buffer, lint.FormatJUnit, results)).To(Succeed())
+
+		var report struct {
+			Suites []struct {
+				Tests    int `xml:"tests,attr"`
+				Failures int `xml:"failures,attr"`
+				Cases    []struct {
+					Name    string `xml:"name,attr"`
+					File    string `xml:"file,attr"`
+					Line    int    `xml:"line,attr"`
+					Failure *struct {
+						Message string `xml:"message,attr"`
+					} `xml:"failure"`
+				} `xml:"testcase"`
+			} `xml:"testsuite"`
+		}
+		Expect(xml.Unmarshal(buffer.Bytes(), &report)).To(Succeed())
+		Expect(report.Suites).To(HaveLen(1))
+		Expect(report.Suites[0].Tests).To(Equal(3))
+		Expect(report.Suites[0].Failures).To(Equal(2))
+		Expect(report.Suites[0].Cases).To(HaveLen(3))
+		Expect(report.Suites[0].Cases[0].Failure).To(BeNil())
+		Expect(report.Suites[0].Cases[1].Name).To(Equal("ConfigMap team-a/invalid"))
+		Expect(report.Suites[0].Cases[1].Line).To(Equal(9))
+		Expect(report.Suites[0].Cases[1].Failure.Message).To(Equal("bad annotation"))
+		Expect(report.Suites[0].Cases[2].Name).To(Equal("missing.yml"))
+	})
+
+	It("rejects unknown formats", func() {
+		Expect(lint.Write(buffer, "yaml", results)).To(MatchError("unknown format 'yaml', expected text, json or junit"))
+	})
+})
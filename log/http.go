@@ -0,0 +1,80 @@
+package log
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+type levels struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
+
+// LevelHandler serves the current log levels on GET and changes them on PUT,
+// e.g. PUT /?level=debug or PUT /?level=debug&package=reconciler. DELETE
+// /?package=reconciler removes a package override. Every request must carry
+// token as a bearer token.
+func LevelHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		pkg := r.URL.Query().Get("package")
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			l, err := ParseLevel(r.URL.Query().Get("level"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if pkg == "" {
+				SetLevel(l)
+			} else {
+				SetPackageLevel(pkg, l)
+			}
+			Info("log level for %s set to %s", packageDescription(pkg), LevelName(l))
+		case http.MethodDelete:
+			if pkg == "" {
+				http.Error(w, "package is required", http.StatusBadRequest)
+				return
+			}
+			ClearPackageLevel(pkg)
+			Info("log level override for %s removed", packageDescription(pkg))
+		default:
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		current := levels{
+			Level:    LevelName(GetLevel()),
+			Packages: map[string]string{},
+		}
+		for p, l := range GetPackageLevels() {
+			current.Packages[p] = LevelName(l)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(current)
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	expected := "Bearer " + token
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) == 1
+}
+
+func packageDescription(pkg string) string {
+	if pkg == "" {
+		return "all packages"
+	}
+	return "package " + pkg
+}
@@ -1,10 +1,19 @@
 package log
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const format = log.Ldate | log.Ltime | log.Lshortfile
@@ -15,15 +24,44 @@ const (
 	LevelError
 )
 
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var levelNames = map[uint8]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelError: "error",
+}
+
+// Fields are the contextual key values attached to a log line.
+type Fields map[string]interface{}
+
+// Entry logs with a fixed set of fields, see With.
+type Entry struct {
+	fields Fields
+}
+
 var (
-	level uint8
-	debug *log.Logger
-	info  *log.Logger
-	err   *log.Logger
+	level         uint32
+	packageLevels atomic.Value
+	outFormat     string
+	redactor      atomic.Value
+	debug         *log.Logger
+	info          *log.Logger
+	err           *log.Logger
+
+	mu     sync.Mutex
+	out    io.Writer
+	errOut io.Writer
 )
 
 func init() {
 	level = LevelInfo
+	packageLevels.Store(map[string]uint8{})
+	redactor.Store(func(s string) string { return s })
+	outFormat = FormatText
 
 	SetOutput(os.Stdout)
 	SetErrOutput(os.Stderr)
@@ -34,32 +72,289 @@ func GetErrLogger() *log.Logger {
 }
 
 func Debug(format string, v ...interface{}) {
-	if level <= LevelDebug {
-		_ = debug.Output(2, fmt.Sprintf(format, v...))
-	}
+	(&Entry{}).output(LevelDebug, format, v...)
 }
 
 func Info(format string, v ...interface{}) {
-	if level <= LevelInfo {
-		_ = info.Output(2, fmt.Sprintf(format, v...))
-	}
+	(&Entry{}).output(LevelInfo, format, v...)
 }
 
 func Error(format string, v ...interface{}) {
-	if level <= LevelError {
-		_ = err.Output(2, fmt.Sprintf(format, v...))
+	(&Entry{}).output(LevelError, format, v...)
+}
+
+func With(fields Fields) *Entry {
+	return (&Entry{}).With(fields)
+}
+
+func (e *Entry) With(fields Fields) *Entry {
+	merged := Fields{}
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Entry{fields: merged}
+}
+
+func (e *Entry) Debug(format string, v ...interface{}) {
+	e.output(LevelDebug, format, v...)
+}
+
+func (e *Entry) Info(format string, v ...interface{}) {
+	e.output(LevelInfo, format, v...)
+}
+
+func (e *Entry) Error(format string, v ...interface{}) {
+	e.output(LevelError, format, v...)
+}
+
+// output must be called directly from the exported logging functions so the
+// caller is reported correctly.
+func (e *Entry) output(l uint8, format string, v ...interface{}) {
+	if !enabled(l) {
+		return
+	}
+
+	redact := redactor.Load().(func(string) string)
+	msg := redact(fmt.Sprintf(format, v...))
+	if outFormat == FormatJSON {
+		e.outputJSON(l, msg, redact)
+		return
+	}
+
+	logger := info
+	switch l {
+	case LevelDebug:
+		logger = debug
+	case LevelError:
+		logger = err
+	}
+	_ = logger.Output(3, msg+e.fieldsText(redact))
+}
+
+func (e *Entry) outputJSON(l uint8, msg string, redact func(string) string) {
+	line := map[string]interface{}{}
+	for k, v := range e.fields {
+		switch value := v.(type) {
+		case error:
+			v = redact(value.Error())
+		case string:
+			v = redact(value)
+		case time.Duration:
+			v = value.Seconds()
+		case fmt.Stringer:
+			v = redact(value.String())
+		}
+		line[k] = v
+	}
+	line["level"] = levelNames[l]
+	line["ts"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["msg"] = msg
+	if _, file, lineNumber, ok := runtime.Caller(3); ok {
+		line["caller"] = fmt.Sprintf("%s:%d", filepath.Base(file), lineNumber)
+	}
+
+	encoded, marshalErr := json.Marshal(line)
+	if marshalErr != nil {
+		encoded, _ = json.Marshal(map[string]string{"level": levelNames[l], "msg": msg, "error": marshalErr.Error()})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	w := out
+	if l == LevelError {
+		w = errOut
+	}
+	_, _ = w.Write(append(encoded, '\n'))
+}
+
+func (e *Entry) fieldsText(redact func(string) string) string {
+	if len(e.fields) == 0 {
+		return ""
+	}
+
+	var keys []string
+	for k := range e.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		value := redact(fmt.Sprintf("%v", e.fields[k]))
+		if value == "" || strings.ContainsAny(value, " =\"") {
+			value = strconv.Quote(value)
+		}
+		fmt.Fprintf(&b, " %s=%s", k, value)
+	}
+	return b.String()
+}
+
+// enabled checks l against the level of the package logging, falling back to
+// the global level. It must be called directly from output.
+func enabled(l uint8) bool {
+	levels := packageLevels.Load().(map[string]uint8)
+	if len(levels) > 0 {
+		if packageLevel, ok := levels[callerPackage(4)]; ok {
+			return packageLevel <= l
+		}
 	}
+	return uint8(atomic.LoadUint32(&level)) <= l
+}
+
+func callerPackage(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return ""
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+
+	// e.g. github.com/aclevername/config-map-controller/reconciler.(*ConfigMapReconciler).ReconcileResource
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	return name
 }
 
 func SetLevel(l uint8) {
-	level = l
+	atomic.StoreUint32(&level, uint32(l))
+}
+
+func GetLevel() uint8 {
+	return uint8(atomic.LoadUint32(&level))
+}
+
+// SetPackageLevel overrides the level for lines logged from the named
+// package, e.g. "controller" or "reconciler".
+func SetPackageLevel(pkg string, l uint8) {
+	updatePackageLevels(func(levels map[string]uint8) {
+		levels[pkg] = l
+	})
+}
+
+func ClearPackageLevel(pkg string) {
+	updatePackageLevels(func(levels map[string]uint8) {
+		delete(levels, pkg)
+	})
+}
+
+func GetPackageLevels() map[string]uint8 {
+	levels := map[string]uint8{}
+	for pkg, l := range packageLevels.Load().(map[string]uint8) {
+		levels[pkg] = l
+	}
+	return levels
+}
+
+func updatePackageLevels(update func(map[string]uint8)) {
+	mu.Lock()
+	defer mu.Unlock()
+	levels := GetPackageLevels()
+	update(levels)
+	packageLevels.Store(levels)
+}
+
+func ParseLevel(name string) (uint8, error) {
+	for l, levelName := range levelNames {
+		if strings.EqualFold(strings.TrimSpace(name), levelName) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level '%s', expected debug, info or error", name)
+}
+
+func LevelName(l uint8) string {
+	return levelNames[l]
+}
+
+// Configure sets the global and per package levels from a spec such as
+// "info" or "info,reconciler=debug". Nothing is changed if the spec is
+// invalid.
+func Configure(spec string) error {
+	global, packages, err := parseSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	SetLevel(global)
+	mu.Lock()
+	packageLevels.Store(packages)
+	mu.Unlock()
+	return nil
+}
+
+// ValidateSpec checks a spec accepted by Configure without applying it.
+func ValidateSpec(spec string) error {
+	_, _, err := parseSpec(spec)
+	return err
+}
+
+func parseSpec(spec string) (uint8, map[string]uint8, error) {
+	global := GetLevel()
+	packages := map[string]uint8{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		pkg := ""
+		name := part
+		if i := strings.Index(part, "="); i >= 0 {
+			pkg, name = strings.TrimSpace(part[:i]), part[i+1:]
+			if pkg == "" {
+				return 0, nil, fmt.Errorf("missing package name in log level '%s'", part)
+			}
+		}
+
+		l, err := ParseLevel(name)
+		if err != nil {
+			return 0, nil, err
+		}
+		if pkg == "" {
+			global = l
+		} else {
+			packages[pkg] = l
+		}
+	}
+	return global, packages, nil
+}
+
+// SetFormat switches between the default text output and one JSON object
+// per line.
+func SetFormat(f string) error {
+	switch f {
+	case FormatText, FormatJSON:
+		outFormat = f
+		return nil
+	}
+	return fmt.Errorf("unknown log format '%s', expected %s or %s", f, FormatText, FormatJSON)
+}
+
+// SetRedactor sets a function every message and field value is passed
+// through before being written, e.g. to remove credentials.
+func SetRedactor(redact func(string) string) {
+	redactor.Store(redact)
 }
 
 func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
 	debug = log.New(w, "[DEBUG] ", format)
 	info = log.New(w, "[INFO] ", format)
 }
 
 func SetErrOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	errOut = w
 	err = log.New(w, "[ERROR] ", format)
 }
@@ -0,0 +1,267 @@
+package log_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/onsi/gomega/gbytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Log", func() {
+	var (
+		out    *gbytes.Buffer
+		errOut *gbytes.Buffer
+	)
+
+	BeforeEach(func() {
+		out = gbytes.NewBuffer()
+		errOut = gbytes.NewBuffer()
+		log.SetOutput(out)
+		log.SetErrOutput(errOut)
+		log.SetLevel(log.LevelDebug)
+	})
+
+	AfterEach(func() {
+		log.SetRedactor(func(s string) string { return s })
+		Expect(log.SetFormat(log.FormatText)).To(Succeed())
+		Expect(log.Configure("info")).To(Succeed())
+		log.SetOutput(os.Stdout)
+		log.SetErrOutput(os.Stderr)
+	})
+
+	Describe("SetFormat", func() {
+		It("rejects unknown formats", func() {
+			Expect(log.SetFormat("xml")).To(MatchError("unknown log format 'xml', expected text or json"))
+		})
+	})
+
+	When("the format is text", func() {
+		It("prefixes the level and caller and appends the fields sorted", func() {
+			log.With(log.Fields{"name": "my-configmap", "namespace": "default"}).Info("hello %s", "there")
+			Expect(string(out.Contents())).To(MatchRegexp(`^\[INFO\] \d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} log_test.go:\d+: hello there name=my-configmap namespace=default\n$`))
+		})
+
+		It("quotes values containing spaces", func() {
+			log.With(log.Fields{"error": errors.New("it broke")}).Error("failed")
+			Expect(string(errOut.Contents())).To(HaveSuffix(`failed error="it broke"` + "\n"))
+		})
+
+		It("keeps the printf style functions working", func() {
+			log.Debug("debug %d", 1)
+			log.Error("error %d", 2)
+			Expect(string(out.Contents())).To(MatchRegexp(`^\[DEBUG\] .* log_test.go:\d+: debug 1\n$`))
+			Expect(string(errOut.Contents())).To(MatchRegexp(`^\[ERROR\] .* log_test.go:\d+: error 2\n$`))
+		})
+	})
+
+	When("the format is json", func() {
+		BeforeEach(func() {
+			Expect(log.SetFormat(log.FormatJSON)).To(Succeed())
+		})
+
+		It("writes one object per line with the level, timestamp, caller and fields", func() {
+			log.With(log.Fields{"namespace": "default"}).With(log.Fields{"name": "my-configmap", "duration": 1500 * time.Millisecond}).Info("processed %s", "configmap")
+
+			var line map[string]interface{}
+			Expect(json.Unmarshal(out.Contents(), &line)).To(Succeed())
+			Expect(line).To(HaveKeyWithValue("level", "info"))
+			Expect(line).To(HaveKeyWithValue("msg", "processed configmap"))
+			Expect(line).To(HaveKeyWithValue("namespace", "default"))
+			Expect(line).To(HaveKeyWithValue("name", "my-configmap"))
+			Expect(line).To(HaveKeyWithValue("duration", 1.5))
+			Expect(line["caller"]).To(MatchRegexp(`^log_test.go:\d+$`))
+
+			ts, err := time.Parse(time.RFC3339Nano, line["ts"].(string))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ts).To(BeTemporally("~", time.Now(), time.Minute))
+		})
+
+		It("writes errors to the error output", func() {
+			log.With(log.Fields{"error": errors.New("it broke")}).Error("failed")
+			Expect(out.Contents()).To(BeEmpty())
+
+			var line map[string]interface{}
+			Expect(json.Unmarshal(errOut.Contents(), &line)).To(Succeed())
+			Expect(line).To(HaveKeyWithValue("level", "error"))
+			Expect(line).To(HaveKeyWithValue("error", "it broke"))
+		})
+
+		It("reports the caller of the printf style functions", func() {
+			log.Info("hello")
+
+			var line map[string]interface{}
+			Expect(json.Unmarshal(out.Contents(), &line)).To(Succeed())
+			Expect(line["caller"]).To(MatchRegexp(`^log_test.go:\d+$`))
+		})
+	})
+
+	It("drops lines below the level", func() {
+		log.SetLevel(log.LevelError)
+		log.Info("hidden")
+		log.With(log.Fields{"a": "b"}).Debug("hidden")
+		Expect(out.Contents()).To(BeEmpty())
+	})
+
+	Describe("levels", func() {
+		It("parses level names", func() {
+			l, err := log.ParseLevel("Debug")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(l).To(Equal(uint8(log.LevelDebug)))
+
+			_, err = log.ParseLevel("verbose")
+			Expect(err).To(MatchError("unknown log level 'verbose', expected debug, info or error"))
+		})
+
+		It("lets a package override the global level", func() {
+			log.SetLevel(log.LevelError)
+			log.SetPackageLevel("log_test", log.LevelDebug)
+			log.Debug("shown")
+			Expect(string(out.Contents())).To(ContainSubstring("shown"))
+
+			log.ClearPackageLevel("log_test")
+			log.Debug("hidden")
+			Expect(string(out.Contents())).NotTo(ContainSubstring("hidden"))
+		})
+
+		Describe("Configure", func() {
+			It("sets the global and package levels", func() {
+				Expect(log.Configure("error, reconciler=debug,controller=info")).To(Succeed())
+				Expect(log.GetLevel()).To(Equal(uint8(log.LevelError)))
+				Expect(log.GetPackageLevels()).To(Equal(map[string]uint8{
+					"reconciler": log.LevelDebug,
+					"controller": log.LevelInfo,
+				}))
+			})
+
+			It("defaults to the current global level", func() {
+				log.SetLevel(log.LevelError)
+				Expect(log.Configure("")).To(Succeed())
+				Expect(log.GetLevel()).To(Equal(uint8(log.LevelError)))
+				Expect(log.GetPackageLevels()).To(BeEmpty())
+			})
+
+			It("changes nothing when the spec is invalid", func() {
+				log.SetLevel(log.LevelError)
+				Expect(log.Configure("debug,reconciler=loud")).To(MatchError("unknown log level 'loud', expected debug, info or error"))
+				Expect(log.Configure("=debug")).To(MatchError("missing package name in log level '=debug'"))
+				Expect(log.GetLevel()).To(Equal(uint8(log.LevelError)))
+			})
+
+			It("can validate a spec without applying it", func() {
+				log.SetLevel(log.LevelError)
+				Expect(log.ValidateSpec("debug,reconciler=info")).To(Succeed())
+				Expect(log.ValidateSpec("loud")).To(MatchError("unknown log level 'loud', expected debug, info or error"))
+				Expect(log.GetLevel()).To(Equal(uint8(log.LevelError)))
+			})
+		})
+
+		It("can be changed while logging", func() {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 100; i++ {
+					log.Debug("line %d", i)
+				}
+			}()
+			for i := 0; i < 100; i++ {
+				log.SetLevel(uint8(i % 3))
+				log.SetPackageLevel("controller", uint8(i%3))
+			}
+			Eventually(done).Should(BeClosed())
+		})
+	})
+
+	Describe("LevelHandler", func() {
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(log.LevelHandler("secret"))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		request := func(method, query, token string) (int, string) {
+			req, err := http.NewRequest(method, server.URL+"/?"+query, nil)
+			Expect(err).NotTo(HaveOccurred())
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			resp, err := server.Client().Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred())
+			return resp.StatusCode, string(body)
+		}
+
+		It("rejects requests without the token", func() {
+			code, _ := request(http.MethodPut, "level=debug", "")
+			Expect(code).To(Equal(http.StatusUnauthorized))
+			code, _ = request(http.MethodPut, "level=debug", "wrong")
+			Expect(code).To(Equal(http.StatusUnauthorized))
+			Expect(log.GetLevel()).To(Equal(uint8(log.LevelDebug)))
+		})
+
+		It("reports the current levels", func() {
+			log.SetPackageLevel("reconciler", log.LevelError)
+			code, body := request(http.MethodGet, "", "secret")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{"level":"debug","packages":{"reconciler":"error"}}`))
+		})
+
+		It("changes the global level", func() {
+			code, body := request(http.MethodPut, "level=error", "secret")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(body).To(MatchJSON(`{"level":"error","packages":{}}`))
+			Expect(log.GetLevel()).To(Equal(uint8(log.LevelError)))
+		})
+
+		It("changes and removes a package level", func() {
+			code, _ := request(http.MethodPut, "level=info&package=controller", "secret")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(log.GetPackageLevels()).To(Equal(map[string]uint8{"controller": log.LevelInfo}))
+
+			code, _ = request(http.MethodDelete, "package=controller", "secret")
+			Expect(code).To(Equal(http.StatusOK))
+			Expect(log.GetPackageLevels()).To(BeEmpty())
+		})
+
+		It("rejects unknown levels", func() {
+			code, body := request(http.MethodPut, "level=loud", "secret")
+			Expect(code).To(Equal(http.StatusBadRequest))
+			Expect(body).To(ContainSubstring("unknown log level 'loud'"))
+		})
+	})
+
+	Describe("SetRedactor", func() {
+		BeforeEach(func() {
+			log.SetRedactor(func(s string) string {
+				return strings.Replace(s, "secret", "REDACTED", -1)
+			})
+		})
+
+		It("redacts text messages and fields", func() {
+			log.With(log.Fields{"url": "https://secret@example.com", "error": errors.New("secret failed")}).Error("curling %s", "secret")
+			Expect(string(errOut.Contents())).NotTo(ContainSubstring("secret"))
+			Expect(string(errOut.Contents())).To(HaveSuffix(`curling REDACTED error="REDACTED failed" url=https://REDACTED@example.com` + "\n"))
+		})
+
+		It("redacts json messages and fields", func() {
+			Expect(log.SetFormat(log.FormatJSON)).To(Succeed())
+			log.With(log.Fields{"url": "https://secret@example.com", "error": errors.New("secret failed")}).Error("curling %s", "secret")
+			Expect(string(errOut.Contents())).NotTo(ContainSubstring("secret"))
+		})
+	})
+})
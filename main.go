@@ -1,29 +1,208 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
+
+	"github.com/aclevername/config-map-controller/client/clientset/versioned"
+	"github.com/aclevername/config-map-controller/client/informers/externalversions"
+	"github.com/aclevername/config-map-controller/config"
+	"github.com/aclevername/config-map-controller/election"
+	"github.com/aclevername/config-map-controller/health"
 	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/metrics"
 
 	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/redact"
+	"github.com/aclevername/config-map-controller/remotedata"
+	"github.com/aclevername/config-map-controller/replicate"
+	"github.com/aclevername/config-map-controller/scope"
+	"github.com/aclevername/config-map-controller/source"
+	"github.com/aclevername/config-map-controller/webhook"
 
 	"github.com/aclevername/config-map-controller/controller"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
 )
 
+const annotation = config.DefaultAnnotationKey
+
 func main() {
-	log.SetLevel(0)
-	annotation := "x-k8s.io/curl-me-that"
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "validate":
+			os.Exit(validate(os.Args[2:]))
+		case "render":
+			os.Exit(render(os.Args[2:]))
+		case "sync":
+			os.Exit(syncOnce(os.Args[2:]))
+		}
+	}
 
 	kubeconfig := flag.String("kubeconfig", "", "path to kubeconfig")
+	configFile := flag.String("config", "", "path to a ControllerConfig file, replacing the scope, policy and logging flags. Policy, http, limits and logging.level are reloaded when it changes")
+	configPollInterval := flag.Duration("config-poll-interval", 10*time.Second, "how often to check -config for changes")
+	leaderElect := flag.Bool("leader-elect", false, "only reconcile while holding a Lease, allowing multiple replicas to run")
+	leaseName := flag.String("leader-elect-resource-name", "config-map-controller", "name of the Lease used for leader election")
+	leaseNamespace := flag.String("leader-elect-resource-namespace", v1.NamespaceDefault, "namespace of the Lease used for leader election")
+	leaseDuration := flag.Duration("leader-elect-lease-duration", 15*time.Second, "how long followers wait before trying to take over an unrenewed lease")
+	renewDeadline := flag.Duration("leader-elect-renew-deadline", 10*time.Second, "how long the leader retries renewing the lease before giving up")
+	retryPeriod := flag.Duration("leader-elect-retry-period", 2*time.Second, "how long to wait between attempts to acquire or renew the lease")
+	scopeFromFlags := scopeFlags(flag.CommandLine)
+	metricsAddr := flag.String("metrics-addr", "", "address to serve prometheus metrics on, e.g. :9090. Disabled when empty")
+	healthAddr := flag.String("health-addr", "", "address to serve /healthz and /readyz on, e.g. :8081. Disabled when empty")
+	livenessWindow := flag.Duration("liveness-window", 5*time.Minute, "how long work can be pending without the worker making progress before /healthz fails")
+	reconcileTimeout := flag.Duration("reconcile-timeout", controller.DefaultTimeout, "how long a single reconcile may take before it is cancelled and retried, 0 for no limit")
+	historyRevisions := flag.Int("history-revisions", 0, "keep that many fetched revisions of each data key in a companion configmap for rollbacks, 0 to disable")
+	dryRun := flag.Bool("dry-run", false, "fetch but only log and record Normal events for the updates that would be made")
+	remoteData := flag.Bool("remote-data", false, "also reconcile RemoteData resources in every namespace, needs the CRD in manifests/ installed")
+	featuresFromFlags := featureFlags(flag.CommandLine)
+	metadataOnly := flag.Bool("metadata-only", false, "only cache configmap metadata, fetching the full object when the annotation is present")
+	logFormat := flag.String("log-format", log.FormatText, "log output format, text or json")
+	logLevel := flag.String("log-level", "", "log level, debug, info or error, with optional per package overrides e.g. info,reconciler=debug. Defaults to $LOG_LEVEL or info")
+	logLevelAddr := flag.String("log-level-addr", "", "local address to serve the runtime log level endpoint on, e.g. 127.0.0.1:8082. Requires $LOG_LEVEL_TOKEN. Disabled when empty")
+	redactQueryParams := flag.String("redact-query-params", strings.Join(redact.DefaultQueryParams, ","), "comma separated query parameters whose values are masked in logs and events")
+	redactHeaders := flag.String("redact-headers", strings.Join(redact.DefaultHeaders, ","), "comma separated headers whose values are masked in logs and events")
+	policyFromFlags := policyFlags(flag.CommandLine)
+	webhookAddr := flag.String("webhook-addr", "", "address to serve the validating admission webhook on over https, e.g. :8443. Disabled when empty")
+	webhookCertFile := flag.String("webhook-cert-file", "", "path to the tls certificate for the webhook")
+	webhookKeyFile := flag.String("webhook-key-file", "", "path to the tls key for the webhook")
+	webhookPrefetch := flag.Bool("webhook-prefetch", false, "also serve a mutating admission webhook that fetches the data while configmaps are created")
+	webhookPrefetchTimeout := flag.Duration("webhook-prefetch-timeout", 2*time.Second, "how long the mutating webhook waits for a fetch before leaving the configmap to the controller")
+	printWebhookConfig := flag.Bool("print-webhook-config", false, "print the ValidatingWebhookConfiguration manifest for the webhook, and the MutatingWebhookConfiguration with -webhook-prefetch, and exit")
+	webhookServiceName := flag.String("webhook-service-name", "config-map-controller", "name of the service exposing the webhook, used by -print-webhook-config")
+	webhookServiceNamespace := flag.String("webhook-service-namespace", v1.NamespaceDefault, "namespace of the service exposing the webhook, used by -print-webhook-config")
+	webhookCAFile := flag.String("webhook-ca-file", "", "path to the ca certificate that signed the webhook certificate, used by -print-webhook-config")
+	webhookFailurePolicy := flag.String("webhook-failure-policy", "Ignore", "what the api server does when the validating webhook is unreachable, Ignore or Fail, used by -print-webhook-config")
+	webhookTimeout := flag.Int("webhook-timeout", 5, "seconds the api server waits for the webhook, used by -print-webhook-config")
 	flag.Parse()
+	enabled := featuresFromFlags()
+
+	redactor := redact.New(scope.ParseList(*redactQueryParams), scope.ParseList(*redactHeaders))
+	log.SetRedactor(redactor.String)
+
+	cfg, err := loadConfig(flag.CommandLine, *configFile, func() config.Config {
+		cfg := config.Default()
+		cfg.Scope.Scope = scopeFromFlags()
+		cfg.Scope.MetadataOnly = *metadataOnly
+		cfg.Policy = policyFromFlags()
+		cfg.Logging = config.Logging{Level: *logLevel, Format: *logFormat}
+		cfg.History.Revisions = *historyRevisions
+		return cfg
+	})
+	if err != nil {
+		log.Error("%v", err)
+		os.Exit(1)
+	}
+
+	if err := log.SetFormat(cfg.Logging.Format); err != nil {
+		log.Error("%v", err)
+		os.Exit(1)
+	}
+
+	if err := configureLog(cfg.Logging.Level); err != nil {
+		log.Error("%v", err)
+		os.Exit(1)
+	}
+
+	policy := cfg.Policy
+
+	if *printWebhookConfig {
+		var caBundle []byte
+		if *webhookCAFile != "" {
+			var err error
+			caBundle, err = ioutil.ReadFile(*webhookCAFile)
+			if err != nil {
+				log.Error("failed to read webhook ca file: %v", err)
+				os.Exit(1)
+			}
+		}
+
+		manifestConfig := webhook.ManifestConfig{
+			Name:             "config-map-controller",
+			ServiceName:      *webhookServiceName,
+			ServiceNamespace: *webhookServiceNamespace,
+			CABundle:         caBundle,
+			FailurePolicy:    *webhookFailurePolicy,
+			TimeoutSeconds:   int32(*webhookTimeout),
+		}
+
+		var configurations []interface{}
+		validating, err := webhook.ValidatingWebhookConfiguration(manifestConfig)
+		if err != nil {
+			log.Error("invalid webhook configuration: %v", err)
+			os.Exit(1)
+		}
+		configurations = append(configurations, validating)
+
+		if *webhookPrefetch {
+			if *webhookPrefetchTimeout >= time.Duration(*webhookTimeout)*time.Second {
+				log.Error("-webhook-prefetch-timeout must be shorter than -webhook-timeout")
+				os.Exit(1)
+			}
+			mutating, err := webhook.MutatingWebhookConfiguration(manifestConfig)
+			if err != nil {
+				log.Error("invalid webhook configuration: %v", err)
+				os.Exit(1)
+			}
+			configurations = append(configurations, mutating)
+		}
+
+		for i, configuration := range configurations {
+			manifest, err := yaml.Marshal(configuration)
+			if err != nil {
+				log.Error("failed to marshal webhook configuration: %v", err)
+				os.Exit(1)
+			}
+			if i > 0 {
+				_, _ = os.Stdout.Write([]byte("---\n"))
+			}
+			_, _ = os.Stdout.Write(manifest)
+		}
+		return
+	}
+
+	if *dryRun && *webhookPrefetch {
+		log.Error("-webhook-prefetch can't be used with -dry-run as it changes configmaps as they are created")
+		os.Exit(1)
+	}
+
+	if *dryRun && *remoteData {
+		log.Error("-remote-data can't be used with -dry-run as RemoteData targets are written regardless")
+		os.Exit(1)
+	}
+
+	if *webhookAddr != "" && (*webhookCertFile == "" || *webhookKeyFile == "") {
+		log.Error("-webhook-cert-file and -webhook-key-file are required to serve the webhook")
+		os.Exit(1)
+	}
+
+	if *logLevelAddr != "" {
+		token := os.Getenv("LOG_LEVEL_TOKEN")
+		if token == "" {
+			log.Error("LOG_LEVEL_TOKEN must be set to serve the log level endpoint")
+			os.Exit(1)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/loglevel", log.LevelHandler(token))
+		serve("log level endpoint", *logLevelAddr, mux)
+	}
 
 	if *kubeconfig == "" {
 		flag.PrintDefaults()
@@ -42,23 +221,252 @@ func main() {
 		os.Exit(1)
 	}
 
-	configMapListWatcher := cache.NewListWatchFromClient(clientset.CoreV1().RESTClient(), "configmaps", v1.NamespaceAll, fields.Everything())
+	recorder := metrics.New()
+	recorder.RegisterWorkqueueMetrics()
 
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", recorder.Handler())
+		serve("metrics", *metricsAddr, mux)
+	}
 
-	_, informer := cache.NewIndexerInformer(configMapListWatcher, &v1.ConfigMap{}, 0, cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			queue.Add(obj)
-		},
-		UpdateFunc: func(old interface{}, new interface{}) {
-			queue.Add(new)
-		}}, cache.Indexers{})
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "configmaps")
 
-	r := reconciler.New(clientset, annotation)
-	configMapController := controller.NewConfigMapController(queue, informer, &r)
+	watchScope := cfg.Scope.Scope
+	annotation := cfg.Annotation.Key
 
-	stopCh := make(chan struct{})
+	handler := controller.EnqueueHandler(queue)
+	handler.DeleteFunc = func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return
+		}
+		namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+		recorder.ConfigMapDeleted(namespace, name)
+		// The reconcile of a deleted configmap removes its copies.
+		if len(enabled.replicateFrom) > 0 {
+			queue.Add(key)
+		}
+	}
+
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		log.Error("failed to build metadata client from: %s", *kubeconfig)
+		os.Exit(1)
+	}
+
+	var informer *scope.Informer
+	if cfg.Scope.MetadataOnly {
+		informer, err = scope.NewMetadataInformer(clientset, metadataClient, watchScope, 0, annotation, handler)
+	} else {
+		informer, err = scope.NewInformer(clientset, watchScope, 0, handler)
+	}
+	if err != nil {
+		log.Error("invalid watch scope: %v", err)
+		os.Exit(1)
+	}
+
+	if len(enabled.replicateFrom) > 0 {
+		if err := informer.AddIndexers(cache.Indexers{replicate.IndexName: replicate.IndexFunc}); err != nil {
+			log.Error("failed to index configmaps to replicate: %v", err)
+			os.Exit(1)
+		}
+	}
+	r, replicator := newReconciler(clientset, cfg, enabled, *dryRun, recorder, redactor, informer, func(key string) {
+		queue.Add(key)
+	})
+	// Reconciles are requeued once the refresh is due rather than resyncing
+	// the informer, whose period would only start after each fetch.
+	r.SetRefreshInterval(cfg.Refresh.Interval.Duration)
+	informers := []cache.Controller{informer}
+	if enabled.objectSources {
+		if err := informer.AddIndexers(cache.Indexers{source.IndexName: source.IndexFunc(annotation)}); err != nil {
+			log.Error("failed to index configmaps by source: %v", err)
+			os.Exit(1)
+		}
+		informers = append(informers, source.NewWatcher(metadataClient, enabled.sourceNamespaces(watchScope), informer, func(key string) {
+			queue.Add(key)
+		}))
+	}
+	configMapController := controller.New(queue, controller.ConfigMaps(informer.Get, r), recorder.Controller("configmaps"), informers...)
+	configMapController.SetWorkers(cfg.Workers)
+	configMapController.SetTimeout(*reconcileTimeout)
+
+	var remoteDataController *controller.Controller
+	if *remoteData {
+		client, err := versioned.NewForConfig(config)
+		if err != nil {
+			log.Error("failed to build remotedata client from: %s", *kubeconfig)
+			os.Exit(1)
+		}
+		informer := externalversions.NewSharedInformerFactory(client, 0).Curlme().V1alpha1().RemoteDatas()
+		remoteDataQueue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "remotedata")
+		informer.Informer().AddEventHandler(controller.EnqueueHandler(remoteDataQueue))
+		targetWatcher := remotedata.NewTargetWatcher(metadataClient, func(key string) {
+			remoteDataQueue.Add(key)
+		})
+		remoteDataController = controller.New(remoteDataQueue, remotedata.NewReconciler(clientset, client, informer.Lister(), r, redactor), recorder.Controller("remotedata"), informer.Informer(), targetWatcher)
+		remoteDataController.SetWorkers(cfg.Workers)
+		remoteDataController.SetTimeout(*reconcileTimeout)
+	}
+
+	var validator *webhook.Validator
+	if *webhookAddr != "" {
+		validator = webhook.NewValidator(annotation, r.Fetchers(), policy, redactor)
+		mux := http.NewServeMux()
+		mux.Handle(webhook.ValidatePath, validator)
+		if *webhookPrefetch {
+			mux.Handle(webhook.MutatePath, webhook.NewMutator(r, informer, *webhookPrefetchTimeout))
+		}
+		serveTLS("webhook", *webhookAddr, *webhookCertFile, *webhookKeyFile, mux)
+	}
+
+	checker := health.New()
+	checker.AddLivenessCheck("worker", func() error {
+		return configMapController.Healthy(*livenessWindow)
+	})
+	checker.AddReadinessCheck("informer", func() error {
+		if !configMapController.HasSynced() {
+			return errors.New("informer has not synced")
+		}
+		return nil
+	})
+	if remoteDataController != nil {
+		checker.AddLivenessCheck("remotedata-worker", func() error {
+			return remoteDataController.Healthy(*livenessWindow)
+		})
+		checker.AddReadinessCheck("remotedata-informer", func() error {
+			if !remoteDataController.HasSynced() {
+				return errors.New("remotedata informer has not synced")
+			}
+			return nil
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		log.Debug("received shutdown signal")
+		cancel()
+	}()
+
+	if *configFile != "" {
+		go watchConfig(ctx, *configFile, *configPollInterval, cfg, r, validator)
+	}
+
+	run := func(ctx context.Context) {
+		if remoteDataController != nil {
+			go remoteDataController.Run(ctx)
+		}
+
+		// Until every namespace and copy is known, copies in the namespaces
+		// not seen yet would look stale and be deleted.
+		if replicator != nil {
+			go replicator.Run(ctx.Done())
+			if !cache.WaitForCacheSync(ctx.Done(), replicator.HasSynced) {
+				return
+			}
+		}
+
+		log.Debug("starting controller to watch for %s annotation", annotation)
+		configMapController.Run(ctx)
+	}
+
+	if *healthAddr != "" {
+		serve("health checks", *healthAddr, checker.Handler())
+	}
+
+	if !*leaderElect {
+		run(ctx)
+		return
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Error("failed to get hostname for leader election identity: %v", err)
+		os.Exit(1)
+	}
+
+	elector, err := election.New(clientset, election.Config{
+		LeaseName:      *leaseName,
+		LeaseNamespace: *leaseNamespace,
+		Identity:       hostname + "_" + uuid.New().String(),
+		LeaseDuration:  *leaseDuration,
+		RenewDeadline:  *renewDeadline,
+		RetryPeriod:    *retryPeriod,
+	})
+	if err != nil {
+		log.Error("invalid leader election configuration: %v", err)
+		os.Exit(1)
+	}
+
+	checker.AddReadinessCheck("leader-election", func() error {
+		if !elector.IsLeader() {
+			return errors.New("not the leader")
+		}
+		return nil
+	})
+
+	// Another instance may already be reconciling, so exit rather than wait
+	// for the lease again with informers that stopped.
+	if err := elector.Run(ctx, run); err != nil {
+		log.Error("%v", err)
+		os.Exit(1)
+	}
+}
+
+// scopeFlags registers the flags making up a scope.Scope, returning a function
+// that builds it once flags have been parsed.
+func scopeFlags(flags *flag.FlagSet) func() scope.Scope {
+	namespaces := flags.String("namespaces", "", "comma separated list of namespaces to watch, defaults to all namespaces")
+	excludeNamespaces := flags.String("exclude-namespaces", "", "comma separated list of namespaces to ignore")
+	labelSelector := flags.String("label-selector", "", "only watch configmaps matching this label selector")
+	namespaceSelector := flags.String("namespace-label-selector", "", "only process configmaps in namespaces matching this label selector")
+	return func() scope.Scope {
+		return scope.Scope{
+			Namespaces:        scope.ParseList(*namespaces),
+			ExcludeNamespaces: scope.ParseList(*excludeNamespaces),
+			LabelSelector:     *labelSelector,
+			NamespaceSelector: *namespaceSelector,
+		}
+	}
+}
+
+// policyFlags registers the flags making up a reconciler.Policy, returning a
+// function that builds it once flags have been parsed.
+func policyFlags(flags *flag.FlagSet) func() reconciler.Policy {
+	allowedSchemes := flags.String("allowed-schemes", "http,https", "comma separated url schemes annotations may use, any scheme is allowed when empty")
+	allowedHosts := flags.String("allowed-hosts", "", "comma separated hosts annotations may fetch from, wildcards such as *.example.com are supported. Any host is allowed when empty")
+	deniedHosts := flags.String("denied-hosts", "", "comma separated hosts annotations may never fetch from, wildcards such as *.example.com are supported")
+	return func() reconciler.Policy {
+		return reconciler.Policy{
+			AllowedSchemes: scope.ParseList(*allowedSchemes),
+			AllowedHosts:   scope.ParseList(*allowedHosts),
+			DeniedHosts:    scope.ParseList(*deniedHosts),
+		}
+	}
+}
+
+func serve(name, addr string, handler http.Handler) {
+	go func() {
+		log.Debug("serving %s on %s", name, addr)
+		if err := http.ListenAndServe(addr, handler); err != nil {
+			log.Error("failed to serve %s on %s: %v", name, addr, err)
+			os.Exit(1)
+		}
+	}()
+}
 
-	log.Debug("starting controller to watch for %s annotation", annotation)
-	configMapController.Run(stopCh)
+func serveTLS(name, addr, certFile, keyFile string, handler http.Handler) {
+	go func() {
+		log.Debug("serving %s on %s", name, addr)
+		if err := http.ListenAndServeTLS(addr, certFile, keyFile, handler); err != nil {
+			log.Error("failed to serve %s on %s: %v", name, addr, err)
+			os.Exit(1)
+		}
+	}()
 }
@@ -1,7 +1,12 @@
 package main_test
 
 import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
 
 	"github.com/onsi/gomega/gbytes"
 
@@ -64,6 +69,270 @@ var _ = Describe("Main", func() {
 		})
 	})
 
+	When("the log format is unknown", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--kubeconfig", writeKubeconfig(), "--log-format", "xml")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("unknown log format 'xml', expected text or json"))
+		})
+	})
+
+	When("the LOG_LEVEL environment variable is invalid", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--kubeconfig", writeKubeconfig())
+			cmd.Env = append(os.Environ(), "LOG_LEVEL=loud")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("unknown log level 'loud', expected debug, info or error"))
+		})
+	})
+
+	When("the log level endpoint is enabled without a token", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--kubeconfig", writeKubeconfig(), "--log-level-addr", freeAddr())
+			cmd.Env = append(os.Environ(), "LOG_LEVEL_TOKEN=")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("LOG_LEVEL_TOKEN must be set to serve the log level endpoint"))
+		})
+	})
+
+	When("the validate subcommand is run", func() {
+		It("reports invalid configmaps with their position and exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "validate", "fixtures")
+			stdOut := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, stdOut, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(stdOut.Contents())).To(Equal("fixtures/config-map-invalid.yml:1 (document 1): ConfigMap invalid: invalid x-k8s.io/curl-me-that annotation: invalid url provided: this isn't a url\n2 checked, 1 problems\n"))
+		})
+
+		It("exits zero when every configmap is valid", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "validate", "--format", "json", "fixtures/config-map-valid.yml")
+			stdOut := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, stdOut, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(string(stdOut.Contents())).To(ContainSubstring(`"problems": 0`))
+		})
+
+		It("applies the url policy", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "validate", "--allowed-hosts", "example.com", "fixtures/config-map-valid.yml")
+			stdOut := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, stdOut, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("host curl-a-joke.herokuapp.com is not one of example.com"))
+		})
+
+		It("checks the annotation set in the config file", func() {
+			dir, err := ioutil.TempDir("", "config")
+			Expect(err).NotTo(HaveOccurred())
+			configFile := filepath.Join(dir, "config.yaml")
+			Expect(ioutil.WriteFile(configFile, []byte(`apiVersion: curlme.x-k8s.io/v1alpha1
+kind: ControllerConfig
+annotation:
+  key: example.com/fetch
+`), 0644)).To(Succeed())
+
+			cmd := exec.Command(binaryPath, "validate", "--config", configFile, "fixtures")
+			stdOut := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, stdOut, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("0 problems"))
+		})
+
+		It("exits non-zero without a path", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "validate")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("validate [flags] path..."))
+		})
+	})
+
+	When("the render subcommand is run offline", func() {
+		var responses string
+
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "responses")
+			Expect(err).NotTo(HaveOccurred())
+			responses = filepath.Join(dir, "responses.yml")
+			Expect(ioutil.WriteFile(responses, []byte("https://curl-a-joke.herokuapp.com:\n  status: 200\n  body: a joke\n"), 0644)).To(Succeed())
+		})
+
+		It("prints the rendered configmap", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "render", "-f", "fixtures/config-map-valid.yml", "--offline", "--responses", responses)
+			stdOut := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, stdOut, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("data:\n  hello: world\n  mydata: a joke\n"))
+		})
+
+		It("prints a diff against the input", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "render", "-f", "fixtures/config-map-valid.yml", "--offline", "--responses", responses, "--diff")
+			stdOut := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, stdOut, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(string(stdOut.Contents())).To(HavePrefix("--- input\n+++ rendered\n"))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("\n+  mydata: a joke\n"))
+		})
+
+		It("prints the events of a failed reconcile and exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "render", "-f", "fixtures/config-map-invalid.yml", "--offline", "--responses", responses)
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("error event for configmap invalid: invalid url provided: this isn't a url"))
+		})
+
+		It("exits non-zero without a manifest", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "render", "--offline", "--responses", responses)
+			session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(1))
+		})
+	})
+
+	When("the sync subcommand can't reach the cluster", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "sync", "--kubeconfig", writeKubeconfig())
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(session, 10).Should(gexec.Exit(1))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("failed to list configmaps"))
+		})
+	})
+
+	When("the sync subcommand is given a config file", func() {
+		It("loads it, refusing the flags it replaces", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "sync", "--kubeconfig", writeKubeconfig(), "--config", "config.yaml", "--history-revisions", "3")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("-history-revisions can't be used with -config, set history.revisions in the config file instead"))
+		})
+	})
+
+	When("the sync subcommand is given an unknown format", func() {
+		It("exits non-zero before contacting the cluster", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "sync", "--kubeconfig", writeKubeconfig(), "--format", "xml")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(1))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("unknown format 'xml', expected text or json"))
+		})
+	})
+
+	When("the webhook config is printed", func() {
+		It("prints the ValidatingWebhookConfiguration and exits zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--print-webhook-config", "--webhook-service-namespace", "kube-system")
+			stdOut := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, stdOut, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("kind: ValidatingWebhookConfiguration"))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("namespace: kube-system"))
+		})
+	})
+
+	When("the webhook config is printed with prefetching", func() {
+		It("also prints the MutatingWebhookConfiguration", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--print-webhook-config", "--webhook-prefetch")
+			stdOut := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, stdOut, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).To(Equal(0))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("kind: ValidatingWebhookConfiguration"))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("---\n"))
+			Expect(string(stdOut.Contents())).To(ContainSubstring("kind: MutatingWebhookConfiguration"))
+		})
+
+		It("exits non-zero when the prefetch timeout outlasts the webhook timeout", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--print-webhook-config", "--webhook-prefetch", "--webhook-prefetch-timeout", "10s")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("-webhook-prefetch-timeout must be shorter than -webhook-timeout"))
+		})
+	})
+
+	When("dry run is combined with prefetching", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--kubeconfig", writeKubeconfig(), "--dry-run", "--webhook-prefetch")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("-webhook-prefetch can't be used with -dry-run"))
+		})
+	})
+
+	When("the webhook is enabled without a certificate", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--kubeconfig", writeKubeconfig(), "--webhook-addr", freeAddr())
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("-webhook-cert-file and -webhook-key-file are required to serve the webhook"))
+		})
+	})
+
 	When("the kubeconfig value isn't a real path", func() {
 		It("exits non-zero", func() {
 			var err error
@@ -77,4 +346,136 @@ var _ = Describe("Main", func() {
 			Expect(string(stdErr.Contents())).To(ContainSubstring("failed to build client config from: /path/to/nowhere"))
 		})
 	})
+
+	When("leader election is enabled with a renew deadline longer than the lease duration", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath,
+				"--kubeconfig", writeKubeconfig(),
+				"--leader-elect",
+				"--leader-elect-lease-duration", "5s",
+				"--leader-elect-renew-deadline", "10s",
+			)
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			exitCode := session.ExitCode()
+			Expect(exitCode).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("invalid leader election configuration: leaseDuration must be greater than renewDeadline"))
+		})
+	})
+
+	When("the watch scope is invalid", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath,
+				"--kubeconfig", writeKubeconfig(),
+				"--namespaces", "team-a",
+				"--exclude-namespaces", "team-a",
+			)
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			exitCode := session.ExitCode()
+			Expect(exitCode).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("invalid watch scope: namespace team-a is both watched and excluded"))
+		})
+	})
+
+	When("the config file is invalid", func() {
+		It("exits non-zero listing the invalid fields", func() {
+			dir, err := ioutil.TempDir("", "config")
+			Expect(err).NotTo(HaveOccurred())
+			configFile := filepath.Join(dir, "config.yaml")
+			Expect(ioutil.WriteFile(configFile, []byte(`apiVersion: curlme.x-k8s.io/v1alpha1
+kind: ControllerConfig
+workers: 0
+logging:
+  format: xml
+`), 0644)).To(Succeed())
+
+			cmd := exec.Command(binaryPath, "--kubeconfig", writeKubeconfig(), "--config", configFile)
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("workers: must be at least 1, got 0"))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("logging.format: expected text or json, got 'xml'"))
+		})
+	})
+
+	When("a config file is combined with a flag it replaces", func() {
+		It("exits non-zero", func() {
+			var err error
+			cmd := exec.Command(binaryPath, "--kubeconfig", writeKubeconfig(), "--config", "config.yaml", "--namespaces", "team-a")
+			stdErr := gbytes.NewBuffer()
+			session, err = gexec.Start(cmd, GinkgoWriter, stdErr)
+			Expect(err).NotTo(HaveOccurred())
+			session.Wait()
+			Expect(session.ExitCode()).NotTo(Equal(0))
+			Expect(string(stdErr.Contents())).To(ContainSubstring("-namespaces can't be used with -config, set scope.namespaces in the config file instead"))
+		})
+	})
+
+	When("a health address is provided", func() {
+		It("serves liveness and readiness, not ready until the informer has synced", func() {
+			var err error
+			addr := freeAddr()
+			cmd := exec.Command(binaryPath, "--kubeconfig", writeKubeconfig(), "--health-addr", addr)
+			session, err = gexec.Start(cmd, GinkgoWriter, GinkgoWriter)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() (int, error) {
+				return getStatusCode("http://" + addr + "/healthz")
+			}).Should(Equal(http.StatusOK))
+
+			Consistently(func() (int, error) {
+				return getStatusCode("http://" + addr + "/readyz")
+			}).Should(Equal(http.StatusServiceUnavailable))
+		})
+	})
 })
+
+func writeKubeconfig() string {
+	dir, err := ioutil.TempDir("", "kubeconfig")
+	Expect(err).NotTo(HaveOccurred())
+	path := filepath.Join(dir, "config")
+	err = ioutil.WriteFile(path, []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: local
+  cluster:
+    server: https://127.0.0.1:1
+contexts:
+- name: local
+  context:
+    cluster: local
+    user: local
+current-context: local
+users:
+- name: local
+  user:
+    token: not-a-real-token
+`), 0600)
+	Expect(err).NotTo(HaveOccurred())
+	return path
+}
+
+func freeAddr() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func getStatusCode(url string) (int, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const namespace = "configmap_controller"
+
+// Prometheus records the controller and reconciler metrics and serves them
+// from its own registry.
+type Prometheus struct {
+	registry          *prometheus.Registry
+	reconciles        *prometheus.CounterVec
+	reconcileDuration *prometheus.HistogramVec
+	fetchDuration     *prometheus.HistogramVec
+	fetchSize         *prometheus.HistogramVec
+	lastFetchAge      *prometheus.Desc
+
+	mu        sync.Mutex
+	managed   map[string]struct{}
+	lastFetch map[string]fetch
+}
+
+type fetch struct {
+	namespace string
+	name      string
+	at        time.Time
+}
+
+func New() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		reconciles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "reconciles_total",
+			Help:      "Number of reconciles by controller and result.",
+		}, []string{"controller", "result"}),
+		reconcileDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "reconcile_duration_seconds",
+			Help:      "How long reconciling a key takes, by controller.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"controller"}),
+		fetchDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "fetch_duration_seconds",
+			Help:      "How long fetching the annotated url takes, by host and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"host", "code"}),
+		fetchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "fetch_response_size_bytes",
+			Help:      "Size of the fetched response bodies, by host and status code.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"host", "code"}),
+		lastFetchAge: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "last_successful_fetch_age_seconds"),
+			"Seconds since the data of a configmap was last fetched successfully.",
+			[]string{"namespace", "name"},
+			nil,
+		),
+		managed:   map[string]struct{}{},
+		lastFetch: map[string]fetch{},
+	}
+
+	managedConfigMaps := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "managed_configmaps",
+		Help:      "Number of configmaps carrying the annotation.",
+	}, func() float64 {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return float64(len(p.managed))
+	})
+
+	p.registry.MustRegister(
+		p.reconciles,
+		p.reconcileDuration,
+		p.fetchDuration,
+		p.fetchSize,
+		managedConfigMaps,
+		lastFetchCollector{p},
+	)
+
+	return p
+}
+
+// RegisterWorkqueueMetrics makes every workqueue created afterwards report to
+// this registry. client-go only accepts the first provider it is given.
+func (p *Prometheus) RegisterWorkqueueMetrics() {
+	workqueue.SetProvider(newWorkqueueProvider(p.registry))
+}
+
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+// Controller returns the metrics of the controller with name, which is used
+// as the controller label of its reconciles.
+func (p *Prometheus) Controller(name string) ControllerMetrics {
+	return ControllerMetrics{p: p, name: name}
+}
+
+// ControllerMetrics records the reconciles of a single controller.
+type ControllerMetrics struct {
+	p    *Prometheus
+	name string
+}
+
+func (c ControllerMetrics) ReconcileFinished(result string, duration time.Duration) {
+	c.p.reconciles.WithLabelValues(c.name, result).Inc()
+	c.p.reconcileDuration.WithLabelValues(c.name).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) FetchFinished(host string, statusCode int, size int, duration time.Duration) {
+	code := strconv.Itoa(statusCode)
+	p.fetchDuration.WithLabelValues(host, code).Observe(duration.Seconds())
+	p.fetchSize.WithLabelValues(host, code).Observe(float64(size))
+}
+
+func (p *Prometheus) FetchSucceeded(namespace, name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastFetch[namespace+"/"+name] = fetch{namespace: namespace, name: name, at: time.Now()}
+}
+
+func (p *Prometheus) ConfigMapManaged(namespace, name string, managed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := namespace + "/" + name
+	if managed {
+		p.managed[key] = struct{}{}
+		return
+	}
+	delete(p.managed, key)
+	delete(p.lastFetch, key)
+}
+
+func (p *Prometheus) ConfigMapDeleted(namespace, name string) {
+	p.ConfigMapManaged(namespace, name, false)
+}
+
+type lastFetchCollector struct {
+	p *Prometheus
+}
+
+func (c lastFetchCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.p.lastFetchAge
+}
+
+func (c lastFetchCollector) Collect(ch chan<- prometheus.Metric) {
+	c.p.mu.Lock()
+	defer c.p.mu.Unlock()
+	for _, f := range c.p.lastFetch {
+		ch <- prometheus.MustNewConstMetric(c.p.lastFetchAge, prometheus.GaugeValue, time.Since(f.at).Seconds(), f.namespace, f.name)
+	}
+}
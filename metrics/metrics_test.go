@@ -0,0 +1,88 @@
+package metrics_test
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"time"
+
+	"github.com/aclevername/config-map-controller/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Prometheus", func() {
+	var recorder *metrics.Prometheus
+
+	BeforeEach(func() {
+		recorder = metrics.New()
+	})
+
+	scrape := func() string {
+		server := httptest.NewServer(recorder.Handler())
+		defer server.Close()
+
+		resp, err := server.Client().Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	It("exposes reconcile counts and latency by controller and result", func() {
+		configMaps := recorder.Controller("configmaps")
+		configMaps.ReconcileFinished("success", time.Second)
+		configMaps.ReconcileFinished("success", time.Second)
+		configMaps.ReconcileFinished("error", time.Second)
+		recorder.Controller("remotedata").ReconcileFinished("success", time.Second)
+
+		output := scrape()
+		Expect(output).To(ContainSubstring(`configmap_controller_reconciles_total{controller="configmaps",result="success"} 2`))
+		Expect(output).To(ContainSubstring(`configmap_controller_reconciles_total{controller="configmaps",result="error"} 1`))
+		Expect(output).To(ContainSubstring(`configmap_controller_reconciles_total{controller="remotedata",result="success"} 1`))
+		Expect(output).To(ContainSubstring(`configmap_controller_reconcile_duration_seconds_count{controller="configmaps"} 3`))
+		Expect(output).To(ContainSubstring(`configmap_controller_reconcile_duration_seconds_count{controller="remotedata"} 1`))
+	})
+
+	It("exposes fetch latency and response sizes by host and status code", func() {
+		recorder.FetchFinished("example.com", 200, 100, time.Second)
+
+		output := scrape()
+		Expect(output).To(ContainSubstring(`configmap_controller_fetch_duration_seconds_count{code="200",host="example.com"} 1`))
+		Expect(output).To(ContainSubstring(`configmap_controller_fetch_response_size_bytes_sum{code="200",host="example.com"} 100`))
+	})
+
+	It("counts the managed configmaps", func() {
+		recorder.ConfigMapManaged("default", "a", true)
+		recorder.ConfigMapManaged("default", "a", true)
+		recorder.ConfigMapManaged("default", "b", true)
+		Expect(scrape()).To(ContainSubstring("configmap_controller_managed_configmaps 2"))
+
+		recorder.ConfigMapManaged("default", "a", false)
+		recorder.ConfigMapDeleted("default", "b")
+		Expect(scrape()).To(ContainSubstring("configmap_controller_managed_configmaps 0"))
+	})
+
+	It("exposes the age of the last successful fetch per configmap", func() {
+		recorder.ConfigMapManaged("default", "a", true)
+		recorder.FetchSucceeded("default", "a")
+		Expect(scrape()).To(MatchRegexp(`configmap_controller_last_successful_fetch_age_seconds{name="a",namespace="default"} \d`))
+
+		recorder.ConfigMapDeleted("default", "a")
+		Expect(scrape()).NotTo(ContainSubstring(`configmap_controller_last_successful_fetch_age_seconds{`))
+	})
+
+	It("exposes the workqueue metrics of named queues", func() {
+		recorder.RegisterWorkqueueMetrics()
+		queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test")
+		defer queue.ShutDown()
+		queue.Add("item")
+
+		output := scrape()
+		Expect(output).To(ContainSubstring(`workqueue_adds_total{name="test"} 1`))
+		Expect(output).To(ContainSubstring(`workqueue_depth{name="test"} 1`))
+	})
+})
@@ -0,0 +1,140 @@
+package oneshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+const (
+	StatusUpdated   = "updated"
+	StatusUnchanged = "unchanged"
+	StatusFailed    = "failed"
+
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+//go:generate counterfeiter -o fakes/fake_reconciler.go . Reconciler
+
+type Reconciler interface {
+	Reconcile(ctx context.Context, cm *apiv1.ConfigMap) (bool, error)
+}
+
+// ReconcilerFunc adapts a function to a Reconciler.
+type ReconcilerFunc func(ctx context.Context, cm *apiv1.ConfigMap) (bool, error)
+
+func (f ReconcilerFunc) Reconcile(ctx context.Context, cm *apiv1.ConfigMap) (bool, error) {
+	return f(ctx, cm)
+}
+
+type Result struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Failed    int      `json:"failed"`
+	Results   []Result `json:"results"`
+}
+
+// Run reconciles the configmaps carrying annotationKey, at most concurrency
+// at a time. ConfigMaps not yet started when ctx is done are reported as
+// failed.
+func Run(ctx context.Context, configMaps []apiv1.ConfigMap, annotationKey string, reconciler Reconciler, concurrency int) Report {
+	var annotated []*apiv1.ConfigMap
+	for i := range configMaps {
+		if _, ok := configMaps[i].Annotations[annotationKey]; ok {
+			annotated = append(annotated, &configMaps[i])
+		}
+	}
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]Result, len(annotated))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				results[i] = reconcile(ctx, annotated[i], reconciler)
+			}
+		}()
+	}
+	for i := range annotated {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	report := Report{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case StatusUpdated:
+			report.Updated++
+		case StatusUnchanged:
+			report.Unchanged++
+		default:
+			report.Failed++
+		}
+	}
+	return report
+}
+
+func reconcile(ctx context.Context, configMap *apiv1.ConfigMap, reconciler Reconciler) Result {
+	result := Result{Namespace: configMap.Namespace, Name: configMap.Name}
+	if err := ctx.Err(); err != nil {
+		result.Status = StatusFailed
+		result.Error = fmt.Sprintf("not reconciled: %v", err)
+		return result
+	}
+
+	updated, err := reconciler.Reconcile(ctx, configMap)
+	switch {
+	case err != nil:
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	case updated:
+		result.Status = StatusUpdated
+	default:
+		result.Status = StatusUnchanged
+	}
+	return result
+}
+
+func (r Report) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatText:
+		for _, result := range r.Results {
+			line := fmt.Sprintf("%-9s %s/%s", result.Status, result.Namespace, result.Name)
+			if result.Error != "" {
+				line += ": " + result.Error
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%d configmaps: %d updated, %d unchanged, %d failed\n", len(r.Results), r.Updated, r.Unchanged, r.Failed)
+		return err
+	case FormatJSON:
+		if r.Results == nil {
+			r.Results = []Result{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+	return fmt.Errorf("unknown format '%s', expected %s or %s", format, FormatText, FormatJSON)
+}
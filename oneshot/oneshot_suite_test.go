@@ -0,0 +1,13 @@
+package oneshot_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestOneshot(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Oneshot Suite")
+}
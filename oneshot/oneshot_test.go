@@ -0,0 +1,139 @@
+package oneshot_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/oneshot"
+	"github.com/aclevername/config-map-controller/oneshot/fakes"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Run", func() {
+	var (
+		fakeReconciler *fakes.FakeReconciler
+		configMaps     []apiv1.ConfigMap
+	)
+
+	configMap := func(name string, annotated bool) apiv1.ConfigMap {
+		cm := apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: name}}
+		if annotated {
+			cm.Annotations = map[string]string{"my-annotation": "key=https://example.com"}
+		}
+		return cm
+	}
+
+	BeforeEach(func() {
+		fakeReconciler = new(fakes.FakeReconciler)
+		fakeReconciler.ReconcileStub = func(_ context.Context, cm *apiv1.ConfigMap) (bool, error) {
+			switch cm.Name {
+			case "updated":
+				return true, nil
+			case "failed":
+				return false, errors.New("failed to curl")
+			}
+			return false, nil
+		}
+		configMaps = []apiv1.ConfigMap{
+			configMap("updated", true),
+			configMap("unchanged", true),
+			configMap("failed", true),
+			configMap("unmanaged", false),
+		}
+	})
+
+	It("reconciles the annotated configmaps and reports the outcome of each", func() {
+		report := oneshot.Run(context.Background(), configMaps, "my-annotation", fakeReconciler, 2)
+		Expect(fakeReconciler.ReconcileCallCount()).To(Equal(3))
+		Expect(report).To(Equal(oneshot.Report{
+			Updated:   1,
+			Unchanged: 1,
+			Failed:    1,
+			Results: []oneshot.Result{
+				{Namespace: "team-a", Name: "updated", Status: oneshot.StatusUpdated},
+				{Namespace: "team-a", Name: "unchanged", Status: oneshot.StatusUnchanged},
+				{Namespace: "team-a", Name: "failed", Status: oneshot.StatusFailed, Error: "failed to curl"},
+			},
+		}))
+	})
+
+	It("reconciles at most concurrency configmaps at a time", func() {
+		var running, maxRunning int32
+		fakeReconciler.ReconcileStub = func(_ context.Context, cm *apiv1.ConfigMap) (bool, error) {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return false, nil
+		}
+
+		configMaps = nil
+		for i := 0; i < 10; i++ {
+			configMaps = append(configMaps, configMap("cm", true))
+		}
+		report := oneshot.Run(context.Background(), configMaps, "my-annotation", fakeReconciler, 3)
+		Expect(report.Unchanged).To(Equal(10))
+		Expect(atomic.LoadInt32(&maxRunning)).To(BeNumerically("<=", 3))
+		Expect(atomic.LoadInt32(&maxRunning)).To(BeNumerically(">", 1))
+	})
+
+	It("reports configmaps as failed once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		report := oneshot.Run(ctx, configMaps, "my-annotation", fakeReconciler, 1)
+		Expect(fakeReconciler.ReconcileCallCount()).To(Equal(0))
+		Expect(report.Failed).To(Equal(3))
+		Expect(report.Results[0].Error).To(Equal("not reconciled: context canceled"))
+	})
+})
+
+var _ = Describe("Report", func() {
+	var report oneshot.Report
+
+	BeforeEach(func() {
+		report = oneshot.Report{
+			Updated: 1,
+			Failed:  1,
+			Results: []oneshot.Result{
+				{Namespace: "team-a", Name: "a", Status: oneshot.StatusUpdated},
+				{Namespace: "team-a", Name: "b", Status: oneshot.StatusFailed, Error: "failed to curl"},
+			},
+		}
+	})
+
+	It("writes text", func() {
+		buffer := new(bytes.Buffer)
+		Expect(report.Write(buffer, oneshot.FormatText)).To(Succeed())
+		Expect(buffer.String()).To(Equal(`updated   team-a/a
+failed    team-a/b: failed to curl
+2 configmaps: 1 updated, 0 unchanged, 1 failed
+`))
+	})
+
+	It("writes json", func() {
+		buffer := new(bytes.Buffer)
+		Expect(report.Write(buffer, oneshot.FormatJSON)).To(Succeed())
+
+		var decoded oneshot.Report
+		Expect(json.Unmarshal(buffer.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(report))
+	})
+
+	It("rejects unknown formats", func() {
+		Expect(report.Write(new(bytes.Buffer), "xml")).To(MatchError("unknown format 'xml', expected text or json"))
+	})
+})
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/aclevername/config-map-controller/config"
+	"github.com/aclevername/config-map-controller/history"
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/redact"
+	"github.com/aclevername/config-map-controller/replicate"
+	"github.com/aclevername/config-map-controller/rollout"
+	"github.com/aclevername/config-map-controller/scope"
+	"github.com/aclevername/config-map-controller/source"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// features are the optional parts of the configmap reconciler, shared by the
+// controller and the sync command.
+type features struct {
+	restartWorkloads       bool
+	objectSources          bool
+	objectSourceNamespaces []string
+	replicateFrom          []string
+}
+
+// sourceNamespaces returns the namespaces configmap:// and secret:// urls may
+// copy from, defaulting to the namespaces in watchScope.
+func (f features) sourceNamespaces(watchScope scope.Scope) []string {
+	if len(f.objectSourceNamespaces) > 0 {
+		return f.objectSourceNamespaces
+	}
+	return watchScope.Namespaces
+}
+
+// featureFlags registers the flags enabling the optional parts of the
+// reconciler, returning a function that reads them once flags have been
+// parsed.
+func featureFlags(flags *flag.FlagSet) func() features {
+	restartWorkloads := flags.Bool("restart-workloads", false, "restart the Deployments, StatefulSets and DaemonSets consuming an updated configmap when either opts in, see the README")
+	objectSourceNamespaces := flags.String("object-source-namespaces", "", "comma separated namespaces configmap:// and secret:// urls may copy from, which are watched for changes. Defaults to the namespaces watched for configmaps")
+	objectSources := flags.Bool("object-sources", false, "also copy configmap:// and secret:// urls from ConfigMaps and Secrets allowing it, requeuing the configmaps copying from one when it changes. The schemes must be allowed too")
+	replicateFrom := flags.String("replicate-from", "", "comma separated namespaces, wildcards such as platform-* are supported, whose configmaps may be replicated into the namespaces their annotations select, see the README. Disabled when empty")
+	return func() features {
+		return features{
+			restartWorkloads:       *restartWorkloads,
+			objectSources:          *objectSources,
+			objectSourceNamespaces: scope.ParseList(*objectSourceNamespaces),
+			replicateFrom:          scope.ParseList(*replicateFrom),
+		}
+	}
+}
+
+// newReconciler builds the configmap reconciler for cfg with the enabled
+// features. The replicator is returned when replication is enabled, it must be
+// run and have synced before reconciling. sources are the configmaps it
+// replicates, indexed with replicate.IndexFunc, whose keys are passed to
+// enqueue when they need replicating again.
+func newReconciler(clientset kubernetes.Interface, cfg config.Config, enabled features, dryRun bool, metrics reconciler.Metrics, redactor *redact.Redactor, sources replicate.Sources, enqueue func(key string)) (*reconciler.ConfigMapReconciler, *replicate.Replicator) {
+	r := reconciler.New(clientset, cfg.Annotation.Key, metrics, redactor, cfg.Policy)
+	r.SetDryRun(dryRun)
+	r.SetFetchOptions(cfg.FetchOptions())
+	if cfg.History.Revisions > 0 {
+		r.SetHistory(history.New(clientset, cfg.History.Revisions))
+	}
+	if enabled.restartWorkloads {
+		r.SetRestarter(rollout.New(clientset))
+	}
+	if enabled.objectSources {
+		r.RegisterFetcher(source.NewFetcher(clientset, enabled.sourceNamespaces(cfg.Scope.Scope)), "configmap", "secret")
+	}
+
+	var replicator *replicate.Replicator
+	if len(enabled.replicateFrom) > 0 {
+		replicator = replicate.New(clientset, enabled.replicateFrom, sources, enqueue)
+		r.SetReplicator(replicator)
+	}
+	return &r, replicator
+}
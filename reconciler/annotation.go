@@ -0,0 +1,179 @@
+package reconciler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Entry is a parsed key=url annotation value.
+type Entry struct {
+	Key string
+	URL *url.URL
+	// SHA256 is the hex digest the fetched content must have, if pinned with
+	// a sha256 option.
+	SHA256 string
+	// Signature is set when the content must be signed, with the
+	// signature-key option and one of signature-url or signature-header.
+	Signature *Signature
+}
+
+// Verify checks value against the digest the entry is pinned to, if any.
+func (e Entry) Verify(value string) error {
+	if e.SHA256 == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(value))
+	if actual := hex.EncodeToString(sum[:]); actual != e.SHA256 {
+		return fmt.Errorf("content has sha256 %s, expected %s", actual, e.SHA256)
+	}
+	return nil
+}
+
+// Policy restricts the urls an annotation may point at. Empty lists allow
+// everything.
+type Policy struct {
+	AllowedSchemes []string `json:"allowedSchemes"`
+	AllowedHosts   []string `json:"allowedHosts"`
+	DeniedHosts    []string `json:"deniedHosts"`
+}
+
+// ParseAnnotation parses an annotation value of the form key=url with the
+// built in fetchers, see Registry.ParseAnnotation.
+func ParseAnnotation(annotation string, policy Policy) (Entry, error) {
+	return builtin.ParseAnnotation(annotation, policy)
+}
+
+// ParseURL parses rawURL with the built in fetchers, see Registry.ParseURL.
+func ParseURL(rawURL string, policy Policy) (*url.URL, error) {
+	return builtin.ParseURL(rawURL, policy)
+}
+
+// builtin only holds the fetchers that need no configuration, http and https
+// urls parse the same whether or not they are registered.
+var builtin = Registry{"data": DataFetcher{}}
+
+// ParseAnnotation parses an annotation value of the form key=url, parsing the
+// url with ParseURL. The url may be followed by options separated by
+// semicolons, such as key=url;sha256=<hex> or
+// key=url;signature-key=secret/keys/pub.pem;signature-url=<url>.
+func (r Registry) ParseAnnotation(annotation string, policy Policy) (Entry, error) {
+	splitAnnotation := strings.SplitN(annotation, "=", 2)
+	if len(splitAnnotation) != 2 {
+		return Entry{}, fmt.Errorf("annotation value '%s' does not match expected format key=url", annotation)
+	}
+
+	key := splitAnnotation[0]
+	options := strings.Split(splitAnnotation[1], ";")
+	rawUrl := options[0]
+	if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+		return Entry{}, fmt.Errorf("invalid data key '%s': %s", key, strings.Join(errs, ", "))
+	}
+
+	u, err := r.ParseURL(rawUrl, policy)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{Key: key, URL: u}
+	var signature Signature
+	for _, option := range options[1:] {
+		splitOption := strings.SplitN(option, "=", 2)
+		if len(splitOption) != 2 {
+			return Entry{}, fmt.Errorf("invalid option '%s', expected name=value", option)
+		}
+		switch name, value := splitOption[0], splitOption[1]; name {
+		case "sha256":
+			if decoded, err := hex.DecodeString(value); err != nil || len(decoded) != sha256.Size {
+				return Entry{}, fmt.Errorf("invalid sha256 '%s', expected %d hex characters", value, 2*sha256.Size)
+			}
+			entry.SHA256 = strings.ToLower(value)
+		case "signature-key":
+			if signature.Key, err = parseKeyRef(value); err != nil {
+				return Entry{}, err
+			}
+		case "signature-url":
+			if signature.URL, err = r.ParseURL(value, policy); err != nil {
+				return Entry{}, err
+			}
+		case "signature-header":
+			if value == "" {
+				return Entry{}, errors.New("signature-header must not be empty")
+			}
+			signature.Header = value
+		default:
+			return Entry{}, fmt.Errorf("unknown option '%s'", name)
+		}
+	}
+
+	if signature != (Signature{}) {
+		if signature.Key == (KeyRef{}) {
+			return Entry{}, errors.New("signature-url and signature-header need a signature-key")
+		}
+		if (signature.URL == nil) == (signature.Header == "") {
+			return Entry{}, errors.New("signature-key needs exactly one of signature-url and signature-header")
+		}
+		entry.Signature = &signature
+	}
+
+	return entry, nil
+}
+
+// Check checks u against the policy. Urls without a host, such as data: urls,
+// are only checked against the allowed schemes.
+func (p Policy) Check(u *url.URL) error {
+	if len(p.AllowedSchemes) > 0 && !contains(p.AllowedSchemes, u.Scheme) {
+		return fmt.Errorf("url %s is not allowed: scheme %s is not one of %s", u, u.Scheme, strings.Join(p.AllowedSchemes, ", "))
+	}
+
+	host := u.Hostname()
+	if u.Host == "" {
+		return nil
+	}
+	if matchesHost(p.DeniedHosts, host) {
+		return fmt.Errorf("url %s is not allowed: host %s is denied", u, host)
+	}
+
+	if len(p.AllowedHosts) > 0 && !matchesHost(p.AllowedHosts, host) {
+		return fmt.Errorf("url %s is not allowed: host %s is not one of %s", u, host, strings.Join(p.AllowedHosts, ", "))
+	}
+
+	return nil
+}
+
+// CheckCollision returns an error if key can't be added to the data of
+// configMap because binaryData already uses it.
+func CheckCollision(configMap *apiv1.ConfigMap, key string) error {
+	if _, ok := configMap.BinaryData[key]; ok {
+		return fmt.Errorf("data key '%s' already exists in binaryData", key)
+	}
+	return nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesHost matches host against exact names and wildcards such as
+// *.example.com.
+func matchesHost(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package reconciler_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+
+	apiv1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("ParseAnnotation", func() {
+	It("splits the key from the url on the first =", func() {
+		entry, err := reconciler.ParseAnnotation("my-key=https://example.com/data?a=b&c=d", reconciler.Policy{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Key).To(Equal("my-key"))
+		Expect(entry.URL.String()).To(Equal("https://example.com/data?a=b&c=d"))
+	})
+
+	It("defaults the scheme to https", func() {
+		entry, err := reconciler.ParseAnnotation("my-key=example.com:8443/data", reconciler.Policy{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.URL.String()).To(Equal("https://example.com:8443/data"))
+		Expect(entry.URL.Hostname()).To(Equal("example.com"))
+	})
+
+	It("parses a pinned sha256 after the url", func() {
+		entry, err := reconciler.ParseAnnotation("my-key=https://example.com/data?a=b;sha256=F984E411FB298A3A71833A3503F72D55503BB308B7A2617B5C9E08B412F0BC65", reconciler.Policy{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.URL.String()).To(Equal("https://example.com/data?a=b"))
+		Expect(entry.SHA256).To(Equal("f984e411fb298a3a71833a3503f72d55503bb308b7a2617b5c9e08b412f0bc65"))
+		Expect(entry.Verify("hello-there")).To(Succeed())
+		Expect(entry.Verify("other")).To(MatchError(ContainSubstring("content has sha256 d9298a10")))
+	})
+
+	It("parses a signature after the url", func() {
+		entry, err := reconciler.ParseAnnotation("my-key=https://example.com/data;signature-key=secret/keys/pub.pem;signature-url=https://example.com/data.sig?a=b", reconciler.Policy{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Signature.Key).To(Equal(reconciler.KeyRef{Kind: "secret", Name: "keys", Key: "pub.pem"}))
+		Expect(entry.Signature.URL.String()).To(Equal("https://example.com/data.sig?a=b"))
+	})
+
+	It("checks the signature url against the policy", func() {
+		_, err := reconciler.ParseAnnotation("my-key=https://example.com/data;signature-key=secret/keys/pub.pem;signature-url=https://example.org/data.sig", reconciler.Policy{AllowedHosts: []string{"example.com"}})
+		Expect(err).To(MatchError(ContainSubstring("is not allowed")))
+	})
+
+	DescribeTable("rejecting invalid annotations",
+		func(annotation, expectedErr string) {
+			_, err := reconciler.ParseAnnotation(annotation, reconciler.Policy{})
+			Expect(err).To(MatchError(ContainSubstring(expectedErr)))
+		},
+		Entry("no separator", "this looks wrong", "does not match expected format key=url"),
+		Entry("empty key", "=https://example.com", "invalid data key ''"),
+		Entry("key with invalid characters", "my/key=https://example.com", "invalid data key 'my/key'"),
+		Entry("unparsable url", "my-key=!@£%", "invalid url provided: !@£%"),
+		Entry("url with spaces", "my-key=this isn't a url", "invalid url provided: this isn't a url"),
+		Entry("url without a host", "my-key=https:///data", "invalid url provided: https:///data"),
+		Entry("option without a value", "my-key=https://example.com;sha256", "invalid option 'sha256', expected name=value"),
+		Entry("unknown option", "my-key=https://example.com;md5=abc", "unknown option 'md5'"),
+		Entry("short sha256", "my-key=https://example.com;sha256=abc", "invalid sha256 'abc', expected 64 hex characters"),
+		Entry("invalid signature key", "my-key=https://example.com;signature-key=keys/pub.pem;signature-header=X-Signature", "invalid signature-key 'keys/pub.pem'"),
+		Entry("signature key without a signature", "my-key=https://example.com;signature-key=secret/keys/pub.pem", "needs exactly one of signature-url and signature-header"),
+		Entry("signature without a key", "my-key=https://example.com;signature-header=X-Signature", "need a signature-key"),
+		Entry("signature url and header", "my-key=https://example.com;signature-key=secret/keys/pub.pem;signature-header=X-Signature;signature-url=https://example.com/sig", "needs exactly one of signature-url and signature-header"),
+	)
+
+	DescribeTable("applying the policy",
+		func(policy reconciler.Policy, rawURL string, allowed bool) {
+			_, err := reconciler.ParseAnnotation("my-key="+rawURL, policy)
+			if allowed {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring("is not allowed")))
+			}
+		},
+		Entry("empty policy", reconciler.Policy{}, "ftp://example.com", true),
+		Entry("allowed scheme", reconciler.Policy{AllowedSchemes: []string{"https"}}, "https://example.com", true),
+		Entry("disallowed scheme", reconciler.Policy{AllowedSchemes: []string{"https"}}, "http://example.com", false),
+		Entry("allowed host", reconciler.Policy{AllowedHosts: []string{"example.com"}}, "https://example.com:8443/data", true),
+		Entry("allowed wildcard host", reconciler.Policy{AllowedHosts: []string{"*.example.com"}}, "https://api.example.com", true),
+		Entry("host not allowed", reconciler.Policy{AllowedHosts: []string{"*.example.com"}}, "https://example.org", false),
+		Entry("denied host", reconciler.Policy{DeniedHosts: []string{"169.254.169.254"}}, "http://169.254.169.254/latest", false),
+		Entry("denied host without a scheme", reconciler.Policy{DeniedHosts: []string{"169.254.169.254"}}, "169.254.169.254/latest", false),
+		Entry("denied host case insensitive", reconciler.Policy{DeniedHosts: []string{"metadata.internal"}}, "http://Metadata.Internal", false),
+	)
+})
+
+var _ = Describe("CheckCollision", func() {
+	It("allows keys that are only in data", func() {
+		configMap := &apiv1.ConfigMap{Data: map[string]string{"my-key": "value"}}
+		Expect(reconciler.CheckCollision(configMap, "my-key")).To(Succeed())
+	})
+
+	It("rejects keys already used by binaryData", func() {
+		configMap := &apiv1.ConfigMap{BinaryData: map[string][]byte{"my-key": []byte("value")}}
+		Expect(reconciler.CheckCollision(configMap, "my-key")).To(MatchError("data key 'my-key' already exists in binaryData"))
+	})
+})
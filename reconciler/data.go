@@ -0,0 +1,69 @@
+package reconciler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DataFetcher returns the content embedded in data: urls, e.g.
+// data:,hello%20there or data:text/plain%3Bbase64,aGVsbG8=. A ; in the url
+// has to be escaped as %3B in annotations, where it separates options.
+type DataFetcher struct{}
+
+func (DataFetcher) Fetch(ctx context.Context, u *url.URL, header http.Header) (*Response, error) {
+	contentType, body, err := parseDataURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Body: body, ContentType: contentType}, nil
+}
+
+// ValidateURL checks u is a well formed data: url, which has no host.
+func (DataFetcher) ValidateURL(u *url.URL) error {
+	_, _, err := parseDataURL(u)
+	return err
+}
+
+func parseDataURL(u *url.URL) (string, []byte, error) {
+	if u.Opaque == "" {
+		return "", nil, errors.New("expected data:[<mediatype>][;base64],<data>")
+	}
+	raw := u.Opaque
+	if u.RawQuery != "" || u.ForceQuery {
+		raw += "?" + u.RawQuery
+	}
+	comma := strings.Index(raw, ",")
+	if comma < 0 {
+		return "", nil, errors.New("expected data:[<mediatype>][;base64],<data>")
+	}
+
+	mediaType, err := url.PathUnescape(raw[:comma])
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid media type: %v", err)
+	}
+	encoded := raw[comma+1:]
+
+	isBase64 := strings.HasSuffix(mediaType, ";base64")
+	mediaType = strings.TrimSuffix(mediaType, ";base64")
+	if mediaType == "" {
+		mediaType = "text/plain;charset=US-ASCII"
+	}
+
+	data, err := url.PathUnescape(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid data: %v", err)
+	}
+	if !isBase64 {
+		return mediaType, []byte(data), nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid base64 data: %v", err)
+	}
+	return mediaType, decoded, nil
+}
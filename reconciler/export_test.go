@@ -1,5 +0,0 @@
-package reconciler
-
-func (c *ConfigMapReconciler) SetHTTPClient(client HTTPClient) {
-	c.httpClient = client
-}
@@ -0,0 +1,284 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+	v1 "k8s.io/api/core/v1"
+)
+
+type FakeHistory struct {
+	BackfillStub        func(context.Context, *v1.ConfigMap, string, string) error
+	backfillMutex       sync.RWMutex
+	backfillArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+		arg3 string
+		arg4 string
+	}
+	backfillReturns struct {
+		result1 error
+	}
+	backfillReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RecordStub        func(context.Context, *v1.ConfigMap, string, string) error
+	recordMutex       sync.RWMutex
+	recordArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+		arg3 string
+		arg4 string
+	}
+	recordReturns struct {
+		result1 error
+	}
+	recordReturnsOnCall map[int]struct {
+		result1 error
+	}
+	RevisionStub        func(context.Context, *v1.ConfigMap, string, int) (string, error)
+	revisionMutex       sync.RWMutex
+	revisionArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+		arg3 string
+		arg4 int
+	}
+	revisionReturns struct {
+		result1 string
+		result2 error
+	}
+	revisionReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeHistory) Backfill(arg1 context.Context, arg2 *v1.ConfigMap, arg3 string, arg4 string) error {
+	fake.backfillMutex.Lock()
+	ret, specificReturn := fake.backfillReturnsOnCall[len(fake.backfillArgsForCall)]
+	fake.backfillArgsForCall = append(fake.backfillArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.BackfillStub
+	fakeReturns := fake.backfillReturns
+	fake.recordInvocation("Backfill", []interface{}{arg1, arg2, arg3, arg4})
+	fake.backfillMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeHistory) BackfillCallCount() int {
+	fake.backfillMutex.RLock()
+	defer fake.backfillMutex.RUnlock()
+	return len(fake.backfillArgsForCall)
+}
+
+func (fake *FakeHistory) BackfillCalls(stub func(context.Context, *v1.ConfigMap, string, string) error) {
+	fake.backfillMutex.Lock()
+	defer fake.backfillMutex.Unlock()
+	fake.BackfillStub = stub
+}
+
+func (fake *FakeHistory) BackfillArgsForCall(i int) (context.Context, *v1.ConfigMap, string, string) {
+	fake.backfillMutex.RLock()
+	defer fake.backfillMutex.RUnlock()
+	argsForCall := fake.backfillArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeHistory) BackfillReturns(result1 error) {
+	fake.backfillMutex.Lock()
+	defer fake.backfillMutex.Unlock()
+	fake.BackfillStub = nil
+	fake.backfillReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeHistory) BackfillReturnsOnCall(i int, result1 error) {
+	fake.backfillMutex.Lock()
+	defer fake.backfillMutex.Unlock()
+	fake.BackfillStub = nil
+	if fake.backfillReturnsOnCall == nil {
+		fake.backfillReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.backfillReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeHistory) Record(arg1 context.Context, arg2 *v1.ConfigMap, arg3 string, arg4 string) error {
+	fake.recordMutex.Lock()
+	ret, specificReturn := fake.recordReturnsOnCall[len(fake.recordArgsForCall)]
+	fake.recordArgsForCall = append(fake.recordArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+		arg3 string
+		arg4 string
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.RecordStub
+	fakeReturns := fake.recordReturns
+	fake.recordInvocation("Record", []interface{}{arg1, arg2, arg3, arg4})
+	fake.recordMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeHistory) RecordCallCount() int {
+	fake.recordMutex.RLock()
+	defer fake.recordMutex.RUnlock()
+	return len(fake.recordArgsForCall)
+}
+
+func (fake *FakeHistory) RecordCalls(stub func(context.Context, *v1.ConfigMap, string, string) error) {
+	fake.recordMutex.Lock()
+	defer fake.recordMutex.Unlock()
+	fake.RecordStub = stub
+}
+
+func (fake *FakeHistory) RecordArgsForCall(i int) (context.Context, *v1.ConfigMap, string, string) {
+	fake.recordMutex.RLock()
+	defer fake.recordMutex.RUnlock()
+	argsForCall := fake.recordArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeHistory) RecordReturns(result1 error) {
+	fake.recordMutex.Lock()
+	defer fake.recordMutex.Unlock()
+	fake.RecordStub = nil
+	fake.recordReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeHistory) RecordReturnsOnCall(i int, result1 error) {
+	fake.recordMutex.Lock()
+	defer fake.recordMutex.Unlock()
+	fake.RecordStub = nil
+	if fake.recordReturnsOnCall == nil {
+		fake.recordReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.recordReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeHistory) Revision(arg1 context.Context, arg2 *v1.ConfigMap, arg3 string, arg4 int) (string, error) {
+	fake.revisionMutex.Lock()
+	ret, specificReturn := fake.revisionReturnsOnCall[len(fake.revisionArgsForCall)]
+	fake.revisionArgsForCall = append(fake.revisionArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+		arg3 string
+		arg4 int
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.RevisionStub
+	fakeReturns := fake.revisionReturns
+	fake.recordInvocation("Revision", []interface{}{arg1, arg2, arg3, arg4})
+	fake.revisionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeHistory) RevisionCallCount() int {
+	fake.revisionMutex.RLock()
+	defer fake.revisionMutex.RUnlock()
+	return len(fake.revisionArgsForCall)
+}
+
+func (fake *FakeHistory) RevisionCalls(stub func(context.Context, *v1.ConfigMap, string, int) (string, error)) {
+	fake.revisionMutex.Lock()
+	defer fake.revisionMutex.Unlock()
+	fake.RevisionStub = stub
+}
+
+func (fake *FakeHistory) RevisionArgsForCall(i int) (context.Context, *v1.ConfigMap, string, int) {
+	fake.revisionMutex.RLock()
+	defer fake.revisionMutex.RUnlock()
+	argsForCall := fake.revisionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeHistory) RevisionReturns(result1 string, result2 error) {
+	fake.revisionMutex.Lock()
+	defer fake.revisionMutex.Unlock()
+	fake.RevisionStub = nil
+	fake.revisionReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeHistory) RevisionReturnsOnCall(i int, result1 string, result2 error) {
+	fake.revisionMutex.Lock()
+	defer fake.revisionMutex.Unlock()
+	fake.RevisionStub = nil
+	if fake.revisionReturnsOnCall == nil {
+		fake.revisionReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.revisionReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeHistory) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.backfillMutex.RLock()
+	defer fake.backfillMutex.RUnlock()
+	fake.recordMutex.RLock()
+	defer fake.recordMutex.RUnlock()
+	fake.revisionMutex.RLock()
+	defer fake.revisionMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeHistory) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ reconciler.History = new(FakeHistory)
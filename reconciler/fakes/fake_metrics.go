@@ -0,0 +1,167 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+)
+
+type FakeMetrics struct {
+	ConfigMapManagedStub        func(string, string, bool)
+	configMapManagedMutex       sync.RWMutex
+	configMapManagedArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 bool
+	}
+	FetchFinishedStub        func(string, int, int, time.Duration)
+	fetchFinishedMutex       sync.RWMutex
+	fetchFinishedArgsForCall []struct {
+		arg1 string
+		arg2 int
+		arg3 int
+		arg4 time.Duration
+	}
+	FetchSucceededStub        func(string, string)
+	fetchSucceededMutex       sync.RWMutex
+	fetchSucceededArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeMetrics) ConfigMapManaged(arg1 string, arg2 string, arg3 bool) {
+	fake.configMapManagedMutex.Lock()
+	fake.configMapManagedArgsForCall = append(fake.configMapManagedArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 bool
+	}{arg1, arg2, arg3})
+	stub := fake.ConfigMapManagedStub
+	fake.recordInvocation("ConfigMapManaged", []interface{}{arg1, arg2, arg3})
+	fake.configMapManagedMutex.Unlock()
+	if stub != nil {
+		fake.ConfigMapManagedStub(arg1, arg2, arg3)
+	}
+}
+
+func (fake *FakeMetrics) ConfigMapManagedCallCount() int {
+	fake.configMapManagedMutex.RLock()
+	defer fake.configMapManagedMutex.RUnlock()
+	return len(fake.configMapManagedArgsForCall)
+}
+
+func (fake *FakeMetrics) ConfigMapManagedCalls(stub func(string, string, bool)) {
+	fake.configMapManagedMutex.Lock()
+	defer fake.configMapManagedMutex.Unlock()
+	fake.ConfigMapManagedStub = stub
+}
+
+func (fake *FakeMetrics) ConfigMapManagedArgsForCall(i int) (string, string, bool) {
+	fake.configMapManagedMutex.RLock()
+	defer fake.configMapManagedMutex.RUnlock()
+	argsForCall := fake.configMapManagedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeMetrics) FetchFinished(arg1 string, arg2 int, arg3 int, arg4 time.Duration) {
+	fake.fetchFinishedMutex.Lock()
+	fake.fetchFinishedArgsForCall = append(fake.fetchFinishedArgsForCall, struct {
+		arg1 string
+		arg2 int
+		arg3 int
+		arg4 time.Duration
+	}{arg1, arg2, arg3, arg4})
+	stub := fake.FetchFinishedStub
+	fake.recordInvocation("FetchFinished", []interface{}{arg1, arg2, arg3, arg4})
+	fake.fetchFinishedMutex.Unlock()
+	if stub != nil {
+		fake.FetchFinishedStub(arg1, arg2, arg3, arg4)
+	}
+}
+
+func (fake *FakeMetrics) FetchFinishedCallCount() int {
+	fake.fetchFinishedMutex.RLock()
+	defer fake.fetchFinishedMutex.RUnlock()
+	return len(fake.fetchFinishedArgsForCall)
+}
+
+func (fake *FakeMetrics) FetchFinishedCalls(stub func(string, int, int, time.Duration)) {
+	fake.fetchFinishedMutex.Lock()
+	defer fake.fetchFinishedMutex.Unlock()
+	fake.FetchFinishedStub = stub
+}
+
+func (fake *FakeMetrics) FetchFinishedArgsForCall(i int) (string, int, int, time.Duration) {
+	fake.fetchFinishedMutex.RLock()
+	defer fake.fetchFinishedMutex.RUnlock()
+	argsForCall := fake.fetchFinishedArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeMetrics) FetchSucceeded(arg1 string, arg2 string) {
+	fake.fetchSucceededMutex.Lock()
+	fake.fetchSucceededArgsForCall = append(fake.fetchSucceededArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.FetchSucceededStub
+	fake.recordInvocation("FetchSucceeded", []interface{}{arg1, arg2})
+	fake.fetchSucceededMutex.Unlock()
+	if stub != nil {
+		fake.FetchSucceededStub(arg1, arg2)
+	}
+}
+
+func (fake *FakeMetrics) FetchSucceededCallCount() int {
+	fake.fetchSucceededMutex.RLock()
+	defer fake.fetchSucceededMutex.RUnlock()
+	return len(fake.fetchSucceededArgsForCall)
+}
+
+func (fake *FakeMetrics) FetchSucceededCalls(stub func(string, string)) {
+	fake.fetchSucceededMutex.Lock()
+	defer fake.fetchSucceededMutex.Unlock()
+	fake.FetchSucceededStub = stub
+}
+
+func (fake *FakeMetrics) FetchSucceededArgsForCall(i int) (string, string) {
+	fake.fetchSucceededMutex.RLock()
+	defer fake.fetchSucceededMutex.RUnlock()
+	argsForCall := fake.fetchSucceededArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeMetrics) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.configMapManagedMutex.RLock()
+	defer fake.configMapManagedMutex.RUnlock()
+	fake.fetchFinishedMutex.RLock()
+	defer fake.fetchFinishedMutex.RUnlock()
+	fake.fetchSucceededMutex.RLock()
+	defer fake.fetchSucceededMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeMetrics) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ reconciler.Metrics = new(FakeMetrics)
@@ -0,0 +1,193 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+	v1 "k8s.io/api/core/v1"
+)
+
+type FakeReplicator struct {
+	RemoveStub        func(context.Context, string, string) error
+	removeMutex       sync.RWMutex
+	removeArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}
+	removeReturns struct {
+		result1 error
+	}
+	removeReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ReplicateStub        func(context.Context, *v1.ConfigMap) error
+	replicateMutex       sync.RWMutex
+	replicateArgsForCall []struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+	}
+	replicateReturns struct {
+		result1 error
+	}
+	replicateReturnsOnCall map[int]struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeReplicator) Remove(arg1 context.Context, arg2 string, arg3 string) error {
+	fake.removeMutex.Lock()
+	ret, specificReturn := fake.removeReturnsOnCall[len(fake.removeArgsForCall)]
+	fake.removeArgsForCall = append(fake.removeArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	stub := fake.RemoveStub
+	fakeReturns := fake.removeReturns
+	fake.recordInvocation("Remove", []interface{}{arg1, arg2, arg3})
+	fake.removeMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeReplicator) RemoveCallCount() int {
+	fake.removeMutex.RLock()
+	defer fake.removeMutex.RUnlock()
+	return len(fake.removeArgsForCall)
+}
+
+func (fake *FakeReplicator) RemoveCalls(stub func(context.Context, string, string) error) {
+	fake.removeMutex.Lock()
+	defer fake.removeMutex.Unlock()
+	fake.RemoveStub = stub
+}
+
+func (fake *FakeReplicator) RemoveArgsForCall(i int) (context.Context, string, string) {
+	fake.removeMutex.RLock()
+	defer fake.removeMutex.RUnlock()
+	argsForCall := fake.removeArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeReplicator) RemoveReturns(result1 error) {
+	fake.removeMutex.Lock()
+	defer fake.removeMutex.Unlock()
+	fake.RemoveStub = nil
+	fake.removeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeReplicator) RemoveReturnsOnCall(i int, result1 error) {
+	fake.removeMutex.Lock()
+	defer fake.removeMutex.Unlock()
+	fake.RemoveStub = nil
+	if fake.removeReturnsOnCall == nil {
+		fake.removeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.removeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeReplicator) Replicate(arg1 context.Context, arg2 *v1.ConfigMap) error {
+	fake.replicateMutex.Lock()
+	ret, specificReturn := fake.replicateReturnsOnCall[len(fake.replicateArgsForCall)]
+	fake.replicateArgsForCall = append(fake.replicateArgsForCall, struct {
+		arg1 context.Context
+		arg2 *v1.ConfigMap
+	}{arg1, arg2})
+	stub := fake.ReplicateStub
+	fakeReturns := fake.replicateReturns
+	fake.recordInvocation("Replicate", []interface{}{arg1, arg2})
+	fake.replicateMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeReplicator) ReplicateCallCount() int {
+	fake.replicateMutex.RLock()
+	defer fake.replicateMutex.RUnlock()
+	return len(fake.replicateArgsForCall)
+}
+
+func (fake *FakeReplicator) ReplicateCalls(stub func(context.Context, *v1.ConfigMap) error) {
+	fake.replicateMutex.Lock()
+	defer fake.replicateMutex.Unlock()
+	fake.ReplicateStub = stub
+}
+
+func (fake *FakeReplicator) ReplicateArgsForCall(i int) (context.Context, *v1.ConfigMap) {
+	fake.replicateMutex.RLock()
+	defer fake.replicateMutex.RUnlock()
+	argsForCall := fake.replicateArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeReplicator) ReplicateReturns(result1 error) {
+	fake.replicateMutex.Lock()
+	defer fake.replicateMutex.Unlock()
+	fake.ReplicateStub = nil
+	fake.replicateReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeReplicator) ReplicateReturnsOnCall(i int, result1 error) {
+	fake.replicateMutex.Lock()
+	defer fake.replicateMutex.Unlock()
+	fake.ReplicateStub = nil
+	if fake.replicateReturnsOnCall == nil {
+		fake.replicateReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.replicateReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeReplicator) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.removeMutex.RLock()
+	defer fake.removeMutex.RUnlock()
+	fake.replicateMutex.RLock()
+	defer fake.replicateMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeReplicator) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ reconciler.Replicator = new(FakeReplicator)
@@ -0,0 +1,155 @@
+package reconciler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:generate counterfeiter -o fakes/fake_fetcher.go . Fetcher
+
+// Fetcher fetches the content urls of the schemes it is registered for point
+// at. header is only meaningful to fetchers of protocols that have headers.
+type Fetcher interface {
+	Fetch(ctx context.Context, u *url.URL, header http.Header) (*Response, error)
+}
+
+// Live is implemented by fetchers of sources the controller watches, whose
+// changes requeue the configmaps using them. Their content is fetched again on
+// every reconcile rather than only when a refresh is due.
+type Live interface {
+	Live() bool
+}
+
+// URLValidator is implemented by fetchers whose urls may have no host, such
+// as data: urls, checking the urls of their schemes instead. Urls of the
+// schemes of other fetchers need a host.
+type URLValidator interface {
+	ValidateURL(u *url.URL) error
+}
+
+// Response is fetched content along with what the fetcher knows about it.
+// Metadata a fetcher can't tell is left empty.
+type Response struct {
+	Body         []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+	// Header holds protocol specific metadata, such as the headers of an http
+	// response.
+	Header http.Header
+}
+
+// Registry maps url schemes to the Fetcher for them.
+type Registry map[string]Fetcher
+
+// Register makes fetcher fetch urls of schemes, replacing the fetcher
+// previously registered for them.
+func (r Registry) Register(fetcher Fetcher, schemes ...string) {
+	for _, scheme := range schemes {
+		r[strings.ToLower(scheme)] = fetcher
+	}
+}
+
+// Fetch fetches u with the fetcher registered for its scheme.
+func (r Registry) Fetch(ctx context.Context, u *url.URL, header http.Header) (*Response, error) {
+	if err := r.CheckScheme(u); err != nil {
+		return nil, err
+	}
+	return r[strings.ToLower(u.Scheme)].Fetch(ctx, u, header)
+}
+
+// CheckScheme returns an error unless a fetcher is registered for the scheme
+// of u.
+func (r Registry) CheckScheme(u *url.URL) error {
+	if _, ok := r[strings.ToLower(u.Scheme)]; !ok {
+		return fmt.Errorf("can't fetch %s, scheme %s is not one of %s", u, u.Scheme, strings.Join(r.Schemes(), ", "))
+	}
+	return nil
+}
+
+// ParseURL parses rawURL and checks it against policy. The scheme defaults to
+// https unless rawURL starts with a registered scheme, so example.com:8443/data
+// is fetched over https while data:,hello is left alone.
+func (r Registry) ParseURL(rawURL string, policy Policy) (*url.URL, error) {
+	withScheme := rawURL
+	if !strings.Contains(rawURL, "://") && !r.registered(rawURL) {
+		withScheme = "https://" + rawURL
+	}
+	u, err := url.Parse(withScheme)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url provided: %s", rawURL)
+	}
+	if validator, ok := r[strings.ToLower(u.Scheme)].(URLValidator); ok {
+		if err := validator.ValidateURL(u); err != nil {
+			return nil, fmt.Errorf("invalid url provided: %s: %v", rawURL, err)
+		}
+	} else if u.Host == "" {
+		return nil, fmt.Errorf("invalid url provided: %s", rawURL)
+	}
+
+	if err := policy.Check(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// registered reports whether rawURL starts with a registered scheme followed
+// by a colon.
+func (r Registry) registered(rawURL string) bool {
+	colon := strings.Index(rawURL, ":")
+	if colon <= 0 {
+		return false
+	}
+	_, ok := r[strings.ToLower(rawURL[:colon])]
+	return ok
+}
+
+// Live reports whether the fetcher registered for the scheme of u is Live.
+func (r Registry) Live(u *url.URL) bool {
+	live, ok := r[strings.ToLower(u.Scheme)].(Live)
+	return ok && live.Live()
+}
+
+// Schemes returns the registered schemes in order.
+func (r Registry) Schemes() []string {
+	var schemes []string
+	for scheme := range r {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+type namespaceKey struct{}
+
+// WithNamespace returns a copy of ctx carrying the namespace of the configmap
+// the content is fetched for, so fetchers can check it may be copied there.
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// NamespaceFrom returns the namespace WithNamespace stored in ctx.
+func NamespaceFrom(ctx context.Context) (string, bool) {
+	namespace, ok := ctx.Value(namespaceKey{}).(string)
+	return namespace, ok
+}
+
+type sensitiveHeadersKey struct{}
+
+// WithSensitiveHeaders returns a copy of ctx carrying the names of headers,
+// such as a signature-header, whose values fetchers must not log.
+func WithSensitiveHeaders(ctx context.Context, names ...string) context.Context {
+	return context.WithValue(ctx, sensitiveHeadersKey{}, append(SensitiveHeadersFrom(ctx), names...))
+}
+
+// SensitiveHeadersFrom returns the header names WithSensitiveHeaders stored
+// in ctx.
+func SensitiveHeadersFrom(ctx context.Context) []string {
+	names, _ := ctx.Value(sensitiveHeadersKey{}).([]string)
+	return append([]string{}, names...)
+}
@@ -0,0 +1,128 @@
+package reconciler_test
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+	httpFakes "github.com/aclevername/config-map-controller/reconciler/fakes"
+)
+
+var _ = Describe("Registry", func() {
+	var (
+		registry    reconciler.Registry
+		fakeFetcher *httpFakes.FakeFetcher
+	)
+
+	BeforeEach(func() {
+		fakeFetcher = new(httpFakes.FakeFetcher)
+		fakeFetcher.FetchReturns(&reconciler.Response{Body: []byte("hello-there")}, nil)
+		registry = reconciler.Registry{}
+		registry.Register(fakeFetcher, "HTTPS", "data")
+	})
+
+	It("fetches with the fetcher registered for the scheme", func() {
+		u, err := url.Parse("https://example.com")
+		Expect(err).NotTo(HaveOccurred())
+		header := http.Header{"Accept": {"text/plain"}}
+
+		resp, err := registry.Fetch(context.Background(), u, header)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(resp.Body)).To(Equal("hello-there"))
+
+		_, fetched, fetchedHeader := fakeFetcher.FetchArgsForCall(0)
+		Expect(fetched).To(Equal(u))
+		Expect(fetchedHeader).To(Equal(header))
+	})
+
+	It("fails for unregistered schemes", func() {
+		u, err := url.Parse("file:///etc/passwd")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = registry.Fetch(context.Background(), u, nil)
+		Expect(err).To(MatchError("can't fetch file:///etc/passwd, scheme file is not one of data, https"))
+		Expect(fakeFetcher.FetchCallCount()).To(Equal(0))
+	})
+})
+
+var _ = Describe("Registry.ParseURL", func() {
+	var registry reconciler.Registry
+
+	BeforeEach(func() {
+		registry = reconciler.Registry{}
+		registry.Register(new(httpFakes.FakeFetcher), "http", "https")
+		registry.Register(reconciler.DataFetcher{}, "data")
+		registry.Register(new(hostlessFetcher), "file")
+	})
+
+	It("defaults to https when the url doesn't start with a registered scheme", func() {
+		u, err := registry.ParseURL("localhost:8080/data", reconciler.Policy{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u.String()).To(Equal("https://localhost:8080/data"))
+	})
+
+	It("leaves urls of registered schemes alone", func() {
+		u, err := registry.ParseURL("data:,hello", reconciler.Policy{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u.Scheme).To(Equal("data"))
+	})
+
+	It("lets the fetcher validate urls without a host", func() {
+		u, err := registry.ParseURL("file:///etc/config", reconciler.Policy{AllowedHosts: []string{"example.com"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(u.Path).To(Equal("/etc/config"))
+
+		_, err = registry.ParseURL("data:hello", reconciler.Policy{})
+		Expect(err).To(MatchError("invalid url provided: data:hello: expected data:[<mediatype>][;base64],<data>"))
+	})
+
+	It("needs a host for fetchers that don't validate urls", func() {
+		_, err := registry.ParseURL("https:///data", reconciler.Policy{})
+		Expect(err).To(MatchError("invalid url provided: https:///data"))
+	})
+
+	It("checks the scheme of urls without a host against the policy", func() {
+		_, err := registry.ParseURL("data:,hello", reconciler.Policy{AllowedSchemes: []string{"https"}})
+		Expect(err).To(MatchError(ContainSubstring("scheme data is not one of https")))
+	})
+})
+
+var _ = Describe("DataFetcher", func() {
+	fetch := func(rawURL string) (*reconciler.Response, error) {
+		u, err := url.Parse(rawURL)
+		Expect(err).NotTo(HaveOccurred())
+		return reconciler.DataFetcher{}.Fetch(context.Background(), u, nil)
+	}
+
+	It("returns percent encoded data", func() {
+		resp, err := fetch("data:,hello%20there?")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(resp.Body)).To(Equal("hello there?"))
+		Expect(resp.ContentType).To(Equal("text/plain;charset=US-ASCII"))
+	})
+
+	It("decodes base64 data, with the ; escaped", func() {
+		resp, err := fetch("data:application/json%3Bbase64,eyJhIjoxfQ==")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(resp.Body)).To(Equal(`{"a":1}`))
+		Expect(resp.ContentType).To(Equal("application/json"))
+	})
+
+	It("rejects invalid base64", func() {
+		_, err := fetch("data:;base64,!!")
+		Expect(err).To(MatchError(ContainSubstring("invalid base64 data")))
+	})
+})
+
+// hostlessFetcher fetches urls without a host, such as file:///etc/config.
+type hostlessFetcher struct {
+	httpFakes.FakeFetcher
+}
+
+func (*hostlessFetcher) ValidateURL(u *url.URL) error {
+	return nil
+}
@@ -0,0 +1,136 @@
+package reconciler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/redact"
+)
+
+//go:generate counterfeiter -o fakes/fake_http_client.go . HTTPClient
+
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// FetchOptions control how data is fetched. Zero values mean no timeout, no
+// User-Agent header and no size limit.
+type FetchOptions struct {
+	Timeout          time.Duration
+	UserAgent        string
+	MaxResponseBytes int64
+}
+
+// HTTPFetcher fetches http and https urls, recording metrics for each fetch.
+// Urls and headers are redacted before they are logged.
+type HTTPFetcher struct {
+	client   HTTPClient
+	metrics  Metrics
+	redactor *redact.Redactor
+
+	mu      sync.RWMutex
+	options FetchOptions
+}
+
+func NewHTTPFetcher(client HTTPClient, metrics Metrics, redactor *redact.Redactor) *HTTPFetcher {
+	return &HTTPFetcher{client: client, metrics: metrics, redactor: redactor}
+}
+
+// SetClient replaces the client used to fetch, e.g. to replay recorded
+// responses.
+func (f *HTTPFetcher) SetClient(client HTTPClient) {
+	f.client = client
+}
+
+// SetOptions replaces the options used for each fetch. It is safe to call
+// while fetching.
+func (f *HTTPFetcher) SetOptions(options FetchOptions) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.options = options
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, u *url.URL, header http.Header) (*Response, error) {
+	var (
+		statusCode int
+		size       int
+	)
+	start := time.Now()
+	defer func() {
+		f.metrics.FetchFinished(u.Host, statusCode, size, time.Since(start))
+	}()
+
+	f.mu.RLock()
+	options := f.options
+	f.mu.RUnlock()
+
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	url := u.String()
+	req, err := http.NewRequestWithContext(ctx, "GET", url, &bytes.Buffer{})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request, err: %v", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if options.UserAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", options.UserAgent)
+	}
+
+	sensitive := SensitiveHeadersFrom(ctx)
+	logger := log.With(log.Fields{"url": f.redactor.URL(u), "request_header": f.redactor.Header(req.Header, sensitive...)})
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		logger.Debug("fetch failed")
+		return nil, fmt.Errorf("failed to curl %s, got error: %v", url, err)
+	}
+	statusCode = resp.StatusCode
+	logger.With(log.Fields{"status_code": resp.StatusCode, "response_header": f.redactor.Header(resp.Header, sensitive...)}).Debug("fetched")
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("failed to curl %s, got status code: %d", url, resp.StatusCode)
+	}
+
+	if resp.Body == nil {
+		return nil, fmt.Errorf("empty response body from %s", url)
+	}
+	defer resp.Body.Close()
+	var body io.Reader = resp.Body
+	if options.MaxResponseBytes > 0 {
+		body = io.LimitReader(resp.Body, options.MaxResponseBytes+1)
+	}
+	respValue, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	size = len(respValue)
+	if options.MaxResponseBytes > 0 && int64(size) > options.MaxResponseBytes {
+		return nil, fmt.Errorf("response body from %s is larger than %d bytes", url, options.MaxResponseBytes)
+	}
+
+	response := &Response{
+		Body:        respValue,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        resp.Header.Get("ETag"),
+		Header:      resp.Header,
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		response.LastModified = lastModified
+	}
+	return response, nil
+}
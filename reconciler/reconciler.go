@@ -1,17 +1,22 @@
 package reconciler
 
 import (
-	"bytes"
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/aclevername/config-map-controller/history"
 	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/redact"
 
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -21,98 +26,531 @@ import (
 
 type ConfigMapReconciler struct {
 	clientset     kubernetes.Interface
-	httpClient    HTTPClient
+	http          *HTTPFetcher
+	fetchers      Registry
 	annotationKey string
+	metrics       Metrics
+	redactor      *redact.Redactor
+	dryRun        bool
+	refresh       time.Duration
+	restarter     Restarter
+	history       History
+	replicator    Replicator
+	settings      *settings
 }
 
-func New(clientset kubernetes.Interface, annotationKey string) ConfigMapReconciler {
+// settings can be changed while the reconciler is running. It is shared by
+// copies of the reconciler.
+type settings struct {
+	mu          sync.RWMutex
+	policy      Policy
+	lastFetched map[string]time.Time
+	// recorded holds the sha256 of the value of each data key last known
+	// to be in the history, by namespace/name/key.
+	recorded map[string][sha256.Size]byte
+}
+
+func New(clientset kubernetes.Interface, annotationKey string, metrics Metrics, redactor *redact.Redactor, policy Policy) ConfigMapReconciler {
+	httpFetcher := NewHTTPFetcher(&http.Client{}, metrics, redactor)
+	fetchers := Registry{}
+	fetchers.Register(httpFetcher, "http", "https")
+	fetchers.Register(DataFetcher{}, "data")
+
 	return ConfigMapReconciler{
 		clientset:     clientset,
-		httpClient:    &http.Client{},
+		http:          httpFetcher,
+		fetchers:      fetchers,
 		annotationKey: annotationKey,
+		metrics:       metrics,
+		redactor:      redactor,
+		settings: &settings{
+			policy:      policy,
+			lastFetched: map[string]time.Time{},
+			recorded:    map[string][sha256.Size]byte{},
+		},
 	}
 }
 
-//go:generate counterfeiter -o fakes/fake_http_client.go . HTTPClient
+// SetPolicy replaces the policy urls are checked against. It is safe to call
+// while reconciling.
+func (c *ConfigMapReconciler) SetPolicy(policy Policy) {
+	c.settings.mu.Lock()
+	defer c.settings.mu.Unlock()
+	c.settings.policy = policy
+}
+
+// SetFetchOptions replaces the options used for each fetch. It is safe to
+// call while reconciling.
+func (c *ConfigMapReconciler) SetFetchOptions(options FetchOptions) {
+	c.http.SetOptions(options)
+}
+
+// SetRefreshInterval makes the reconciler fetch data keys that are already
+// set again once interval has passed since they were last fetched by it,
+// updating them when the content changed. Zero, the default, never refetches.
+func (c *ConfigMapReconciler) SetRefreshInterval(interval time.Duration) {
+	c.refresh = interval
+}
+
+// SetHTTPClient replaces the client used to fetch, e.g. to replay recorded
+// responses.
+func (c *ConfigMapReconciler) SetHTTPClient(client HTTPClient) {
+	c.http.SetClient(client)
+}
+
+// RegisterFetcher makes the reconciler fetch urls of schemes with fetcher,
+// replacing the fetcher of http and https urls if they are among them.
+func (c *ConfigMapReconciler) RegisterFetcher(fetcher Fetcher, schemes ...string) {
+	c.fetchers.Register(fetcher, schemes...)
+}
+
+// Fetchers returns the registry annotations are parsed and fetched with,
+// which also holds the fetchers registered later.
+func (c *ConfigMapReconciler) Fetchers() Registry {
+	return c.fetchers
+}
+
+// SetDryRun makes the reconciler fetch but log and record an event for the
+// update it would make instead of making it. Events are recorded as Normal
+// and marked as dry-run.
+func (c *ConfigMapReconciler) SetDryRun(dryRun bool) {
+	c.dryRun = dryRun
+}
+
+// SetRestarter makes the reconciler restart the workloads consuming a
+// configmap once it has updated it.
+func (c *ConfigMapReconciler) SetRestarter(restarter Restarter) {
+	c.restarter = restarter
+}
+
+// SetHistory makes the reconciler record each fetched revision in history
+// and honour the rollback annotation.
+func (c *ConfigMapReconciler) SetHistory(history History) {
+	c.history = history
+}
+
+// SetReplicator makes the reconciler replicate the data of configmaps into
+// the namespaces they select once it is in place.
+func (c *ConfigMapReconciler) SetReplicator(replicator Replicator) {
+	c.replicator = replicator
+}
+
+//go:generate counterfeiter -o fakes/fake_restarter.go . Restarter
+
+type Restarter interface {
+	Restart(ctx context.Context, configMap *apiv1.ConfigMap) error
+}
+
+//go:generate counterfeiter -o fakes/fake_history.go . History
+
+// History keeps the fetched revisions of data keys.
+type History interface {
+	Record(ctx context.Context, configMap *apiv1.ConfigMap, key, value string) error
+	Backfill(ctx context.Context, configMap *apiv1.ConfigMap, key, value string) error
+	Revision(ctx context.Context, configMap *apiv1.ConfigMap, key string, revision int) (string, error)
+}
+
+//go:generate counterfeiter -o fakes/fake_replicator.go . Replicator
+
+// Replicator keeps copies of configmaps in other namespaces.
+type Replicator interface {
+	Replicate(ctx context.Context, configMap *apiv1.ConfigMap) error
+	Remove(ctx context.Context, namespace, name string) error
+}
+
+//go:generate counterfeiter -o fakes/fake_metrics.go . Metrics
 
-type HTTPClient interface {
-	Do(*http.Request) (*http.Response, error)
+type Metrics interface {
+	FetchFinished(host string, statusCode int, size int, duration time.Duration)
+	FetchSucceeded(namespace, name string)
+	ConfigMapManaged(namespace, name string, managed bool)
 }
 
-func (c *ConfigMapReconciler) ReconcileResource(cm *apiv1.ConfigMap) error {
+// ReconcileResource fetches the data the annotation on cm points at into it.
+// The fetch is cancelled once ctx is done, and the configmap is left alone.
+func (c *ConfigMapReconciler) ReconcileResource(ctx context.Context, cm *apiv1.ConfigMap) error {
+	_, err := c.Sync(ctx, cm)
+	return err
+}
+
+// Sync is ReconcileResource, also reporting whether the configmap was
+// updated. Unlike Reconcile, it replicates the configmap too.
+func (c *ConfigMapReconciler) Sync(ctx context.Context, cm *apiv1.ConfigMap) (bool, error) {
+	updated, err := c.Reconcile(ctx, cm)
+	if err != nil || c.replicator == nil || c.dryRun {
+		return updated, err
+	}
+	// Copies of a configmap that is no longer managed are left to no one.
+	if _, ok := cm.Annotations[c.annotationKey]; !ok {
+		return updated, c.replicator.Remove(ctx, cm.Namespace, cm.Name)
+	}
+
+	// The copies are made from what was fetched into cm, so the content is
+	// fetched once however many namespaces it is replicated to.
+	if updated {
+		if err := ctx.Err(); err != nil {
+			return updated, err
+		}
+		cm, err = c.clientset.CoreV1().ConfigMaps(cm.Namespace).Get(cm.Name, metav1.GetOptions{})
+		if err != nil {
+			return updated, fmt.Errorf("failed to get updated configmap: %v", err)
+		}
+	}
+	if err := c.replicator.Replicate(ctx, cm); err != nil {
+		return updated, c.addEventLogAndError(ctx, fmt.Sprintf("failed to replicate: %v", err), cm)
+	}
+	return updated, nil
+}
+
+// ReconcileDeleted removes the copies of a deleted configmap.
+func (c *ConfigMapReconciler) ReconcileDeleted(ctx context.Context, namespace, name string) error {
+	if c.replicator == nil || c.dryRun {
+		return nil
+	}
+	return c.replicator.Remove(ctx, namespace, name)
+}
+
+// Reconcile is ReconcileResource, also reporting whether the configmap was
+// updated.
+func (c *ConfigMapReconciler) Reconcile(ctx context.Context, cm *apiv1.ConfigMap) (bool, error) {
 	configMap := cm.DeepCopy()
+	logger := log.With(log.Fields{
+		"namespace":    configMap.Namespace,
+		"name":         configMap.Name,
+		"reconcile_id": uuid.New().String(),
+	})
+
 	annotation, ok := configMap.Annotations[c.annotationKey]
+	c.metrics.ConfigMapManaged(configMap.Namespace, configMap.Name, ok)
 	if !ok {
-		log.Debug("no annotation found on %s/%s", configMap.Namespace, configMap.Name)
-		return nil
+		logger.Debug("no annotation found")
+		return false, nil
 	}
 
-	splitAnnotation := strings.Split(annotation, "=")
-	if len(splitAnnotation) != 2 {
-		return c.addEventLogAndError(
-			fmt.Sprintf("annotation value '%s' does not match expected format key=url", annotation),
-			configMap,
-		)
+	entry, err := c.parse(annotation, configMap)
+	if err != nil {
+		return false, c.addEventLogAndError(ctx, err.Error(), configMap)
 	}
 
-	key := splitAnnotation[0]
-	rawUrl := splitAnnotation[1]
-	u, err := url.Parse(rawUrl)
+	key := entry.Key
+	logger = logger.With(log.Fields{"data_key": key, "url_host": entry.URL.Host})
+
+	if rollback, ok := configMap.Annotations[history.RollbackAnnotation]; ok {
+		return c.rollback(ctx, logger, cm, configMap, key, rollback)
+	}
+
+	current, ok := configMap.Data[key]
+	if ok && !c.refreshDue(configMap) && !c.fetchers.Live(entry.URL) {
+		logger.Debug("data field already set")
+		c.backfillHistory(ctx, logger, configMap, key, current)
+		return false, nil
+	}
+
+	resp, err := c.curl(fetchContext(ctx, configMap, entry), entry.URL)
 	if err != nil {
-		return c.addEventLogAndError(
-			fmt.Sprintf("invalid url provided: %s", rawUrl),
-			configMap,
-		)
+		return false, c.addEventLogAndError(ctx, err.Error(), configMap)
 	}
+	value := string(resp.Body)
+	c.metrics.FetchSucceeded(configMap.Namespace, configMap.Name)
+	c.fetched(configMap)
 
-	if u.Scheme == "" {
-		u.Scheme = "https"
+	// A mismatch leaves the data alone, so a refresh never replaces content
+	// that was verified with content that can't be.
+	fingerprint, err := c.verify(ctx, configMap, entry, value, resp.Header)
+	if err != nil {
+		msg := c.redactor.String(fmt.Sprintf("refusing to set data key %s from %s: %v", key, c.redactor.URL(entry.URL), err))
+		logger.With(log.Fields{"error": err}).Error("fetched content failed verification")
+		c.addEvent(ctx, configMap, apiv1.EventTypeWarning, msg)
+		return false, errors.New(msg)
 	}
 
-	_, ok = configMap.Data[key]
-	if ok {
-		log.Debug("data field %s already set on %s/%s", key, configMap.Namespace, configMap.Name)
-		return nil
+	if ok && value == current && configMap.Annotations[VerifiedKeyAnnotation] == fingerprint {
+		logger.Debug("refreshed data unchanged")
+		c.backfillHistory(ctx, logger, configMap, key, current)
+		return false, nil
 	}
 
-	value, errMsg := curl(u.String(), c.httpClient)
-	if errMsg != "" {
-		return c.addEventLogAndError(
-			errMsg,
-			configMap,
-		)
+	if fingerprint != "" {
+		configMap.Annotations[VerifiedKeyAnnotation] = fingerprint
+	} else {
+		delete(configMap.Annotations, VerifiedKeyAnnotation)
+	}
+	setData(configMap, key, value)
+	updated, err := c.update(ctx, logger, cm, configMap, fmt.Sprintf("set data key %s from %s (%d bytes)", key, c.redactor.URL(entry.URL), len(value)))
+	if err != nil || c.dryRun {
+		return updated, err
 	}
 
-	if configMap.Data == nil {
-		configMap.Data = map[string]string{
-			key: value,
+	// Like restarts, failing to record the history doesn't undo the update.
+	if c.history != nil {
+		if err := c.history.Record(ctx, configMap, key, value); err != nil {
+			logger.With(log.Fields{"error": err}).Error("failed to record history")
+			c.addEvent(ctx, configMap, apiv1.EventTypeWarning, c.redactor.String(fmt.Sprintf("failed to record history: %v", err)))
+		} else {
+			c.recorded(configMap, key, value)
 		}
-	} else {
-		configMap.Data[key] = value
 	}
+	return true, nil
+}
+
+// backfillHistory records data the reconciler didn't set itself, such as
+// data added by the prefetch webhook, or set before it started. The history
+// is only read the first time each value is seen.
+func (c *ConfigMapReconciler) backfillHistory(ctx context.Context, logger *log.Entry, configMap *apiv1.ConfigMap, key, value string) {
+	if c.history == nil || c.dryRun {
+		return
+	}
+	c.settings.mu.RLock()
+	recorded, ok := c.settings.recorded[configMap.Namespace+"/"+configMap.Name+"/"+key]
+	c.settings.mu.RUnlock()
+	if ok && recorded == sha256.Sum256([]byte(value)) {
+		return
+	}
+
+	if err := c.history.Backfill(ctx, configMap, key, value); err != nil {
+		logger.With(log.Fields{"error": err}).Error("failed to record history")
+		c.addEvent(ctx, configMap, apiv1.EventTypeWarning, c.redactor.String(fmt.Sprintf("failed to record history: %v", err)))
+		return
+	}
+	c.recorded(configMap, key, value)
+}
+
+func (c *ConfigMapReconciler) recorded(configMap *apiv1.ConfigMap, key, value string) {
+	c.settings.mu.Lock()
+	defer c.settings.mu.Unlock()
+	c.settings.recorded[configMap.Namespace+"/"+configMap.Name+"/"+key] = sha256.Sum256([]byte(value))
+}
+
+// rollback restores a revision of key from the history. Nothing is fetched
+// while the annotation is set, pinning the configmap to the revision.
+func (c *ConfigMapReconciler) rollback(ctx context.Context, logger *log.Entry, cm, configMap *apiv1.ConfigMap, key, annotation string) (bool, error) {
+	if c.history == nil {
+		return false, c.addEventLogAndError(ctx, fmt.Sprintf("can't roll back data key %s, no history is kept", key), configMap)
+	}
+
+	revision, err := history.ParseRollback(annotation)
+	if err != nil {
+		return false, c.addEventLogAndError(ctx, err.Error(), configMap)
+	}
+
+	value, err := c.history.Revision(ctx, configMap, key, revision)
+	if err != nil {
+		return false, c.addEventLogAndError(ctx, err.Error(), configMap)
+	}
+
+	if current, ok := configMap.Data[key]; ok && current == value {
+		logger.Debug("pinned to revision %d", revision)
+		return false, nil
+	}
+
+	setData(configMap, key, value)
+	updated, err := c.update(ctx, logger, cm, configMap, fmt.Sprintf("roll back data key %s to revision %d", key, revision))
+	if err == nil && !c.dryRun {
+		c.addEvent(ctx, configMap, apiv1.EventTypeNormal, fmt.Sprintf("rolled back data key %s to revision %d", key, revision))
+	}
+	return updated, err
+}
 
-	_, err = c.clientset.CoreV1().ConfigMaps(configMap.ObjectMeta.Namespace).Update(configMap)
+// update writes configMap, which is cm with change applied, and restarts the
+// workloads consuming it. In a dry run the change is only logged and
+// recorded as an event.
+func (c *ConfigMapReconciler) update(ctx context.Context, logger *log.Entry, cm, configMap *apiv1.ConfigMap, change string) (bool, error) {
+	if c.dryRun {
+		logger.Info("dry run, not updating configmap:\n%s", dataChanges(cm.Data, configMap.Data))
+		c.addEvent(ctx, configMap, apiv1.EventTypeNormal, "would "+change)
+		return true, nil
+	}
+
+	// client-go calls don't take a context yet, so at least don't start the
+	// update once the reconcile has been cancelled.
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	updated, err := c.clientset.CoreV1().ConfigMaps(configMap.ObjectMeta.Namespace).Update(configMap)
 	if err != nil {
-		return c.addEventLogAndError(
+		return false, c.addEventLogAndError(
+			ctx,
 			fmt.Sprintf("failed to update configmap: %v", err),
 			configMap,
 		)
 	}
 
-	log.Debug("successfully updated %s/%s", configMap.Namespace, configMap.Name)
+	logger.Debug("successfully updated")
+
+	// The data is in place, so a failed restart is only reported through the
+	// events the restarter records rather than retrying the reconcile.
+	if c.restarter != nil {
+		if err := c.restarter.Restart(ctx, updated); err != nil {
+			logger.With(log.Fields{"error": err}).Error("failed to restart consuming workloads")
+		}
+	}
+
+	return true, nil
+}
+
+func setData(configMap *apiv1.ConfigMap, key, value string) {
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[key] = value
+}
+
+// Fetched is the data Fetch fetched for a configmap.
+type Fetched struct {
+	Key   string
+	Value string
+	// VerifiedKey is the fingerprint of the key the signature of the data was
+	// verified with, to record in VerifiedKeyAnnotation. It is empty when the
+	// data isn't signed.
+	VerifiedKey string
+}
+
+// Fetch fetches the data the annotation on configMap points at without
+// updating it. The key is empty when there is nothing to fetch.
+func (c *ConfigMapReconciler) Fetch(ctx context.Context, configMap *apiv1.ConfigMap) (Fetched, error) {
+	annotation, ok := configMap.Annotations[c.annotationKey]
+	if !ok {
+		return Fetched{}, nil
+	}
+
+	entry, err := c.parse(annotation, configMap)
+	if err != nil {
+		return Fetched{}, err
+	}
 
-	return nil
+	if _, ok := configMap.Data[entry.Key]; ok {
+		return Fetched{}, nil
+	}
+
+	resp, err := c.curl(fetchContext(ctx, configMap, entry), entry.URL)
+	if err != nil {
+		return Fetched{}, err
+	}
+	fingerprint, err := c.verify(ctx, configMap, entry, string(resp.Body), resp.Header)
+	if err != nil {
+		return Fetched{}, err
+	}
+	return Fetched{Key: entry.Key, Value: string(resp.Body), VerifiedKey: fingerprint}, nil
+}
+
+// verify checks value against the checksum and signature of entry, returning
+// the fingerprint of the key the signature was verified with, if any.
+func (c *ConfigMapReconciler) verify(ctx context.Context, configMap *apiv1.ConfigMap, entry Entry, value string, header http.Header) (string, error) {
+	if err := entry.Verify(value); err != nil {
+		return "", err
+	}
+	if entry.Signature == nil {
+		return "", nil
+	}
+	return c.verifySignature(ctx, configMap, entry.Signature, value, header)
 }
 
-func (c *ConfigMapReconciler) addEventLogAndError(errMsg string, configMap *apiv1.ConfigMap) error {
+// refreshDue reports whether the data of configMap, which is already set,
+// should be fetched again.
+func (c *ConfigMapReconciler) refreshDue(configMap *apiv1.ConfigMap) bool {
+	if c.refresh <= 0 {
+		return false
+	}
+	c.settings.mu.RLock()
+	defer c.settings.mu.RUnlock()
+	return time.Since(c.settings.lastFetched[configMap.Namespace+"/"+configMap.Name]) >= c.refresh
+}
+
+// RefreshAfter returns how long until the data of configMap is due to be
+// fetched again, zero when refreshing is disabled or it isn't managed. A
+// configmap that was never fetched is due an interval from now.
+func (c *ConfigMapReconciler) RefreshAfter(configMap *apiv1.ConfigMap) time.Duration {
+	if c.refresh <= 0 {
+		return 0
+	}
+	if _, ok := configMap.Annotations[c.annotationKey]; !ok {
+		return 0
+	}
+	c.settings.mu.RLock()
+	lastFetched, ok := c.settings.lastFetched[configMap.Namespace+"/"+configMap.Name]
+	c.settings.mu.RUnlock()
+	if !ok {
+		return c.refresh
+	}
+	if wait := c.refresh - time.Since(lastFetched); wait > 0 {
+		return wait
+	}
+	return c.refresh
+}
+
+func (c *ConfigMapReconciler) fetched(configMap *apiv1.ConfigMap) {
+	if c.refresh <= 0 {
+		return
+	}
+	c.settings.mu.Lock()
+	defer c.settings.mu.Unlock()
+	c.settings.lastFetched[configMap.Namespace+"/"+configMap.Name] = time.Now()
+}
+
+// FetchURL checks rawURL against the policy and fetches it with the fetch
+// options, adding header to the request. It lets other controllers share the
+// settings of the reconciler.
+func (c *ConfigMapReconciler) FetchURL(ctx context.Context, rawURL string, header http.Header) (string, error) {
+	u, err := c.fetchers.ParseURL(rawURL, c.policy())
+	if err != nil {
+		return "", err
+	}
+	return c.get(ctx, u, header)
+}
+
+// fetchContext returns the context to fetch entry for configMap with, which
+// tells fetchers the namespace it is for and not to log its signature header.
+func fetchContext(ctx context.Context, configMap *apiv1.ConfigMap, entry Entry) context.Context {
+	ctx = WithNamespace(ctx, configMap.Namespace)
+	if entry.Signature != nil && entry.Signature.Header != "" {
+		ctx = WithSensitiveHeaders(ctx, entry.Signature.Header)
+	}
+	return ctx
+}
+
+func (c *ConfigMapReconciler) policy() Policy {
+	c.settings.mu.RLock()
+	defer c.settings.mu.RUnlock()
+	return c.settings.policy
+}
+
+func (c *ConfigMapReconciler) parse(annotation string, configMap *apiv1.ConfigMap) (Entry, error) {
+	entry, err := c.fetchers.ParseAnnotation(annotation, c.policy())
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := CheckCollision(configMap, entry.Key); err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+func (c *ConfigMapReconciler) addEventLogAndError(ctx context.Context, errMsg string, configMap *apiv1.ConfigMap) error {
+	errMsg = c.redactor.String(errMsg)
+	c.addEvent(ctx, configMap, "error", errMsg)
+	return errors.New(errMsg)
+}
+
+// addEvent records an event on configMap, unless ctx is done as the reconcile
+// was cancelled rather than failed.
+func (c *ConfigMapReconciler) addEvent(ctx context.Context, configMap *apiv1.ConfigMap, eventType, message string) {
+	if ctx.Err() != nil {
+		return
+	}
+
 	uniqueID := uuid.New()
 
 	var event apiv1.Event
 	event.Source = apiv1.EventSource{Component: "config-map-controller"}
 	event.Name = "config-map-controller" + uniqueID.String()
-	event.Message = errMsg
+	event.Message = message
 	event.Reason = "-"
-	event.Type = "error"
+	event.Type = eventType
+	if c.dryRun {
+		event.Message = "[dry-run] " + message
+		event.Reason = "DryRun"
+		event.Type = apiv1.EventTypeNormal
+	}
 	event.FirstTimestamp = metav1.Now()
 	event.InvolvedObject = apiv1.ObjectReference{
 		Kind:      "ConfigMap",
@@ -123,33 +561,54 @@ func (c *ConfigMapReconciler) addEventLogAndError(errMsg string, configMap *apiv
 
 	_, err := c.clientset.CoreV1().Events(configMap.ObjectMeta.Namespace).Create(&event)
 	if err != nil {
-		log.Error("error creating event: %s", err.Error())
+		log.With(log.Fields{"namespace": configMap.Namespace, "name": configMap.Name, "error": err}).Error("error creating event")
 	}
-	return errors.New(errMsg)
 }
 
-func curl(url string, httpClient HTTPClient) (string, string) {
-	req, err := http.NewRequest("GET", url, &bytes.Buffer{})
-
-	if err != nil {
-		return "", fmt.Sprintf("failed to create http request, err: %v", err)
+// dataChanges lists the keys that differ between two versions of a
+// configmap's data. Values may be secret, so only their size and a short
+// sha256 are shown.
+func dataChanges(before, after map[string]string) string {
+	var keys []string
+	for key := range before {
+		keys = append(keys, key)
 	}
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Sprintf("failed to curl %s, got error: %v", url, err)
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
 
-	if resp.StatusCode != 200 {
-		return "", fmt.Sprintf("failed to curl %s, got status code: %d", url, resp.StatusCode)
+	var lines []string
+	for _, key := range keys {
+		old, hadOld := before[key]
+		value, hasValue := after[key]
+		switch {
+		case !hadOld:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", key, summarize(value)))
+		case !hasValue:
+			lines = append(lines, fmt.Sprintf("- %s: %s", key, summarize(old)))
+		case old != value:
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", key, summarize(old), summarize(value)))
+		}
 	}
+	return strings.Join(lines, "\n")
+}
 
-	if resp.Body == nil {
-		return "", fmt.Sprintf("empty response body from %s", url)
-	}
-	respValue, err := ioutil.ReadAll(resp.Body)
+func summarize(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("%d bytes, sha256 %x", len(value), sum[:4])
+}
+
+func (c *ConfigMapReconciler) curl(ctx context.Context, u *url.URL) (*Response, error) {
+	return c.fetchers.Fetch(ctx, u, nil)
+}
+
+func (c *ConfigMapReconciler) get(ctx context.Context, u *url.URL, header http.Header) (string, error) {
+	resp, err := c.fetchers.Fetch(ctx, u, header)
 	if err != nil {
-		return "", fmt.Sprintf("failed to read response body: %v", err)
+		return "", err
 	}
-	return string(respValue), ""
+	return string(resp.Body), nil
 }
@@ -0,0 +1,156 @@
+package reconciler
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VerifiedKeyAnnotation records the fingerprint of the key the data was last
+// verified with.
+const VerifiedKeyAnnotation = "x-k8s.io/verified-key"
+
+// Signature is a detached signature the fetched content must verify against,
+// read from SignatureURL or from the SignatureHeader of the response.
+type Signature struct {
+	Key    KeyRef
+	URL    *url.URL
+	Header string
+}
+
+// KeyRef points at a PEM encoded ed25519 or ECDSA public key in a data key of
+// a ConfigMap or Secret in the namespace of the configmap being reconciled.
+type KeyRef struct {
+	Kind string
+	Name string
+	Key  string
+}
+
+func (k KeyRef) String() string {
+	return k.Kind + "/" + k.Name + "/" + k.Key
+}
+
+// parseKeyRef parses a reference of the form configmap/<name>/<key> or
+// secret/<name>/<key>.
+func parseKeyRef(value string) (KeyRef, error) {
+	parts := strings.Split(value, "/")
+	if len(parts) != 3 || (parts[0] != "configmap" && parts[0] != "secret") || parts[1] == "" || parts[2] == "" {
+		return KeyRef{}, fmt.Errorf("invalid signature-key '%s', expected configmap/<name>/<key> or secret/<name>/<key>", value)
+	}
+	return KeyRef{Kind: parts[0], Name: parts[1], Key: parts[2]}, nil
+}
+
+// verifySignature checks value against the signature of entry, returning the
+// fingerprint of the key it was verified with. The signature is base64
+// encoded, over the content for ed25519 keys and over its sha256 for ECDSA
+// keys.
+func (c *ConfigMapReconciler) verifySignature(ctx context.Context, configMap *apiv1.ConfigMap, signature *Signature, value string, header http.Header) (string, error) {
+	key, err := c.publicKey(configMap.Namespace, signature.Key)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := header.Get(signature.Header)
+	if signature.URL != nil {
+		encoded, err = c.get(WithNamespace(ctx, configMap.Namespace), signature.URL, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch signature: %v", err)
+		}
+	} else if encoded == "" {
+		return "", fmt.Errorf("response has no %s header", signature.Header)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return "", fmt.Errorf("signature is not valid base64: %v", err)
+	}
+
+	if err := verify(key, []byte(value), sig); err != nil {
+		return "", fmt.Errorf("signature verification with %s failed: %v", signature.Key, err)
+	}
+	return Fingerprint(key)
+}
+
+func (c *ConfigMapReconciler) publicKey(namespace string, ref KeyRef) (interface{}, error) {
+	var (
+		data []byte
+		ok   bool
+	)
+	switch ref.Kind {
+	case "secret":
+		secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signature key %s: %v", ref, err)
+		}
+		data, ok = secret.Data[ref.Key]
+	default:
+		configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signature key %s: %v", ref, err)
+		}
+		var value string
+		value, ok = configMap.Data[ref.Key]
+		data = []byte(value)
+	}
+	if !ok {
+		return nil, fmt.Errorf("signature key %s not found", ref)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signature key %s is not PEM encoded", ref)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature key %s: %v", ref, err)
+	}
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("signature key %s is neither an ed25519 nor an ECDSA key", ref)
+	}
+}
+
+func verify(key interface{}, content, sig []byte) error {
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, content, sig) {
+			return errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		var rs struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) > 0 {
+			return errors.New("signature is not ASN.1 DER encoded")
+		}
+		digest := sha256.Sum256(content)
+		if !ecdsa.Verify(key, digest[:], rs.R, rs.S) {
+			return errors.New("invalid signature")
+		}
+	}
+	return nil
+}
+
+// Fingerprint returns the sha256 of the DER encoding of key.
+func Fingerprint(key interface{}) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,199 @@
+package reconciler_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+	httpFakes "github.com/aclevername/config-map-controller/reconciler/fakes"
+	"github.com/aclevername/config-map-controller/redact"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Signature verification", func() {
+	const content = "hello-there"
+
+	var (
+		r          reconciler.ConfigMapReconciler
+		fakeClient *fake.Clientset
+		server     *httptest.Server
+		signature  []byte
+		configMap  *apiv1.ConfigMap
+		publicKey  interface{}
+		keys       []runtime.Object
+	)
+
+	BeforeEach(func() {
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		publicKey = pub
+		signature = ed25519.Sign(priv, []byte(content))
+		keys = []runtime.Object{&apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: "my-namespace"},
+			Data:       map[string][]byte{"pub.pem": encodePublicKey(pub)},
+		}}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/data":
+				w.Header().Set("X-Signature", base64.StdEncoding.EncodeToString(signature))
+				_, _ = w.Write([]byte(content))
+			case "/data.sig":
+				_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(signature) + "\n"))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		configMap = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "my-resource",
+				Namespace:   "my-namespace",
+				Annotations: map[string]string{"my-annotation": "my-key=" + server.URL + "/data;signature-key=secret/keys/pub.pem;signature-url=" + server.URL + "/data.sig"},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		fakeClient = fake.NewSimpleClientset(append(keys, configMap)...)
+		r = reconciler.New(fakeClient, "my-annotation", new(httpFakes.FakeMetrics), redact.New(nil, nil), reconciler.Policy{})
+	})
+
+	getConfigMap := func() *apiv1.ConfigMap {
+		cm, err := fakeClient.CoreV1().ConfigMaps("my-namespace").Get("my-resource", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return cm
+	}
+
+	It("sets content signed by an ed25519 key and records its fingerprint", func() {
+		updated, err := r.Reconcile(context.Background(), configMap)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated).To(BeTrue())
+
+		fingerprint, err := reconciler.Fingerprint(publicKey)
+		Expect(err).NotTo(HaveOccurred())
+		cm := getConfigMap()
+		Expect(cm.Data).To(Equal(map[string]string{"my-key": content}))
+		Expect(cm.Annotations).To(HaveKeyWithValue(reconciler.VerifiedKeyAnnotation, fingerprint))
+	})
+
+	It("fetches the signature url for the namespace of the configmap", func() {
+		fakeFetcher := new(httpFakes.FakeFetcher)
+		fakeFetcher.FetchReturns(&reconciler.Response{Body: []byte(base64.StdEncoding.EncodeToString(signature))}, nil)
+		r.RegisterFetcher(fakeFetcher, "sig")
+		configMap.Annotations["my-annotation"] = "my-key=" + server.URL + "/data;signature-key=secret/keys/pub.pem;signature-url=sig://example.com/data.sig"
+
+		_, err := r.Reconcile(context.Background(), configMap)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeFetcher.FetchCallCount()).To(Equal(1))
+		ctx, _, _ := fakeFetcher.FetchArgsForCall(0)
+		namespace, ok := reconciler.NamespaceFrom(ctx)
+		Expect(ok).To(BeTrue())
+		Expect(namespace).To(Equal("my-namespace"))
+	})
+
+	It("returns the fingerprint of the key when prefetching", func() {
+		fetched, err := r.Fetch(context.Background(), configMap)
+		Expect(err).NotTo(HaveOccurred())
+		fingerprint, err := reconciler.Fingerprint(publicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetched).To(Equal(reconciler.Fetched{Key: "my-key", Value: content, VerifiedKey: fingerprint}))
+	})
+
+	When("the signature is in a header and the key is an ECDSA key in a configmap", func() {
+		BeforeEach(func() {
+			priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			publicKey = &priv.PublicKey
+			digest := sha256.Sum256([]byte(content))
+			signature, err = ecdsa.SignASN1(rand.Reader, priv, digest[:])
+			Expect(err).NotTo(HaveOccurred())
+
+			keys = []runtime.Object{&apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "keys", Namespace: "my-namespace"},
+				Data:       map[string]string{"pub.pem": string(encodePublicKey(publicKey))},
+			}}
+			configMap.Annotations["my-annotation"] = "my-key=" + server.URL + "/data;signature-key=configmap/keys/pub.pem;signature-header=X-Signature"
+		})
+
+		It("verifies the content", func() {
+			updated, err := r.Reconcile(context.Background(), configMap)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated).To(BeTrue())
+
+			fingerprint, err := reconciler.Fingerprint(publicKey)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(getConfigMap().Annotations).To(HaveKeyWithValue(reconciler.VerifiedKeyAnnotation, fingerprint))
+		})
+	})
+
+	When("the signature doesn't match the content", func() {
+		BeforeEach(func() {
+			_, priv, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			signature = ed25519.Sign(priv, []byte(content))
+		})
+
+		It("keeps the existing data and records a warning", func() {
+			updated, err := r.Reconcile(context.Background(), configMap)
+			Expect(err).To(MatchError(ContainSubstring("signature verification with secret/keys/pub.pem failed: invalid signature")))
+			Expect(updated).To(BeFalse())
+
+			cm := getConfigMap()
+			Expect(cm.Data).To(BeEmpty())
+			Expect(cm.Annotations).NotTo(HaveKey(reconciler.VerifiedKeyAnnotation))
+			event := getEvent(fakeClient, "my-namespace")
+			Expect(event.Type).To(Equal(apiv1.EventTypeWarning))
+		})
+	})
+
+	When("the key doesn't exist", func() {
+		BeforeEach(func() {
+			keys = nil
+		})
+
+		It("fails without updating", func() {
+			_, err := r.Reconcile(context.Background(), configMap)
+			Expect(err).To(MatchError(ContainSubstring("failed to get signature key secret/keys/pub.pem")))
+			Expect(getConfigMap().Data).To(BeEmpty())
+		})
+	})
+
+	When("the signature can't be fetched", func() {
+		BeforeEach(func() {
+			configMap.Annotations["my-annotation"] = "my-key=" + server.URL + "/data;signature-key=secret/keys/pub.pem;signature-url=" + server.URL + "/missing.sig"
+		})
+
+		It("fails without updating", func() {
+			_, err := r.Reconcile(context.Background(), configMap)
+			Expect(err).To(MatchError(ContainSubstring("failed to fetch signature")))
+			Expect(getConfigMap().Data).To(BeEmpty())
+		})
+	})
+})
+
+func encodePublicKey(key interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
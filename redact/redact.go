@@ -0,0 +1,143 @@
+package redact
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const Mask = "REDACTED"
+
+var (
+	DefaultQueryParams = []string{"token", "access_token", "api_key", "apikey", "key", "password", "secret", "signature", "sig"}
+	DefaultHeaders     = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+	urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.\-]*://[^\s'"<>]+`)
+
+	// userinfoPattern and queryPattern find credentials in urls written
+	// without a scheme, such as the raw input echoed by parse errors.
+	userinfoPattern = regexp.MustCompile(`[^\s'"<>/:@=]+:[^\s'"<>/@]*@`)
+	queryPattern    = regexp.MustCompile(`\?[^\s'"<>]+`)
+)
+
+// Redactor removes credentials from urls and free text before they are
+// written to logs or events.
+type Redactor struct {
+	queryParams map[string]struct{}
+	headers     map[string]struct{}
+}
+
+func New(queryParams, headers []string) *Redactor {
+	r := &Redactor{
+		queryParams: map[string]struct{}{},
+		headers:     map[string]struct{}{},
+	}
+	for _, param := range queryParams {
+		r.queryParams[strings.ToLower(param)] = struct{}{}
+	}
+	for _, header := range headers {
+		r.headers[http.CanonicalHeaderKey(header)] = struct{}{}
+	}
+	return r
+}
+
+// URL returns u without userinfo and with the values of sensitive query
+// parameters masked. The order of the query parameters is kept.
+func (r *Redactor) URL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	redacted.RawQuery = r.query(u.RawQuery)
+	return redacted.String()
+}
+
+// String redacts every url found in s. Outside of urls with a scheme,
+// userinfo is dropped and sensitive query parameters are masked too.
+func (r *Redactor) String(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range urlPattern.FindAllStringIndex(s, -1) {
+		b.WriteString(r.schemeless(s[last:loc[0]]))
+		match := s[loc[0]:loc[1]]
+		trimmed := strings.TrimRight(match, ".,;:)]}")
+		suffix := match[len(trimmed):]
+
+		u, err := url.Parse(trimmed)
+		if err != nil {
+			b.WriteString(r.fallback(trimmed) + suffix)
+		} else {
+			b.WriteString(r.URL(u) + suffix)
+		}
+		last = loc[1]
+	}
+	b.WriteString(r.schemeless(s[last:]))
+	return b.String()
+}
+
+// Header returns a copy of h with the values of sensitive headers, and of
+// the extra names given, masked.
+func (r *Redactor) Header(h http.Header, extra ...string) http.Header {
+	sensitive := map[string]struct{}{}
+	for _, name := range extra {
+		sensitive[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	redacted := http.Header{}
+	for name, values := range h {
+		_, ok := r.headers[http.CanonicalHeaderKey(name)]
+		if _, extra := sensitive[http.CanonicalHeaderKey(name)]; ok || extra {
+			masked := make([]string, len(values))
+			for i := range masked {
+				masked[i] = Mask
+			}
+			redacted[name] = masked
+			continue
+		}
+		redacted[name] = append([]string{}, values...)
+	}
+	return redacted
+}
+
+func (r *Redactor) query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name := param
+		if j := strings.Index(param, "="); j >= 0 {
+			name = param[:j]
+		}
+		unescaped, err := url.QueryUnescape(name)
+		if err != nil {
+			unescaped = name
+		}
+		if _, ok := r.queryParams[strings.ToLower(unescaped)]; ok {
+			params[i] = name + "=" + Mask
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// schemeless redacts text that holds no url with a scheme.
+func (r *Redactor) schemeless(s string) string {
+	s = userinfoPattern.ReplaceAllString(s, "")
+	return queryPattern.ReplaceAllStringFunc(s, func(match string) string {
+		trimmed := strings.TrimRight(match, ".,;:)]}")
+		return "?" + r.query(trimmed[1:]) + match[len(trimmed):]
+	})
+}
+
+// fallback handles urls that do not parse, dropping anything that looks like
+// userinfo and the whole query.
+func (r *Redactor) fallback(s string) string {
+	schemeEnd := strings.Index(s, "://") + len("://")
+	rest := s[schemeEnd:]
+	if at := strings.LastIndex(rest, "@"); at >= 0 {
+		rest = rest[at+1:]
+	}
+	if q := strings.Index(rest, "?"); q >= 0 {
+		rest = rest[:q+1] + Mask
+	}
+	return s[:schemeEnd] + rest
+}
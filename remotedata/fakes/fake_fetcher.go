@@ -0,0 +1,122 @@
+// Code generated by counterfeiter. DO NOT EDIT.
+package fakes
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/aclevername/config-map-controller/remotedata"
+)
+
+type FakeFetcher struct {
+	FetchURLStub        func(context.Context, string, http.Header) (string, error)
+	fetchURLMutex       sync.RWMutex
+	fetchURLArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 http.Header
+	}
+	fetchURLReturns struct {
+		result1 string
+		result2 error
+	}
+	fetchURLReturnsOnCall map[int]struct {
+		result1 string
+		result2 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeFetcher) FetchURL(arg1 context.Context, arg2 string, arg3 http.Header) (string, error) {
+	fake.fetchURLMutex.Lock()
+	ret, specificReturn := fake.fetchURLReturnsOnCall[len(fake.fetchURLArgsForCall)]
+	fake.fetchURLArgsForCall = append(fake.fetchURLArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 http.Header
+	}{arg1, arg2, arg3})
+	stub := fake.FetchURLStub
+	fakeReturns := fake.fetchURLReturns
+	fake.recordInvocation("FetchURL", []interface{}{arg1, arg2, arg3})
+	fake.fetchURLMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeFetcher) FetchURLCallCount() int {
+	fake.fetchURLMutex.RLock()
+	defer fake.fetchURLMutex.RUnlock()
+	return len(fake.fetchURLArgsForCall)
+}
+
+func (fake *FakeFetcher) FetchURLCalls(stub func(context.Context, string, http.Header) (string, error)) {
+	fake.fetchURLMutex.Lock()
+	defer fake.fetchURLMutex.Unlock()
+	fake.FetchURLStub = stub
+}
+
+func (fake *FakeFetcher) FetchURLArgsForCall(i int) (context.Context, string, http.Header) {
+	fake.fetchURLMutex.RLock()
+	defer fake.fetchURLMutex.RUnlock()
+	argsForCall := fake.fetchURLArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeFetcher) FetchURLReturns(result1 string, result2 error) {
+	fake.fetchURLMutex.Lock()
+	defer fake.fetchURLMutex.Unlock()
+	fake.FetchURLStub = nil
+	fake.fetchURLReturns = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeFetcher) FetchURLReturnsOnCall(i int, result1 string, result2 error) {
+	fake.fetchURLMutex.Lock()
+	defer fake.fetchURLMutex.Unlock()
+	fake.FetchURLStub = nil
+	if fake.fetchURLReturnsOnCall == nil {
+		fake.fetchURLReturnsOnCall = make(map[int]struct {
+			result1 string
+			result2 error
+		})
+	}
+	fake.fetchURLReturnsOnCall[i] = struct {
+		result1 string
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeFetcher) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.fetchURLMutex.RLock()
+	defer fake.fetchURLMutex.RUnlock()
+	copiedInvocations := map[string][][]interface{}{}
+	for key, value := range fake.invocations {
+		copiedInvocations[key] = value
+	}
+	return copiedInvocations
+}
+
+func (fake *FakeFetcher) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ remotedata.Fetcher = new(FakeFetcher)
@@ -0,0 +1,494 @@
+package remotedata
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aclevername/config-map-controller/apis/curlme/v1alpha1"
+	"github.com/aclevername/config-map-controller/client/clientset/versioned"
+	listers "github.com/aclevername/config-map-controller/client/listers/curlme/v1alpha1"
+	"github.com/aclevername/config-map-controller/controller"
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/redact"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+const (
+	ReasonFetched        = "Fetched"
+	ReasonFetchFailed    = "FetchFailed"
+	ReasonInvalid        = "Invalid"
+	ReasonTargetConflict = "TargetConflict"
+	ReasonWriteFailed    = "WriteFailed"
+
+	// TargetLabel marks the targets written by RemoteData, so they can be
+	// watched without watching every ConfigMap and Secret.
+	TargetLabel = "curlme.x-k8s.io/remotedata-target"
+
+	// ChecksumAnnotation on a target holds the sha256 of the data last written
+	// to it. A target whose data no longer matches was changed by someone
+	// else, and its sources are fetched again to restore it.
+	ChecksumAnnotation = "curlme.x-k8s.io/checksum"
+)
+
+//go:generate counterfeiter -o fakes/fake_fetcher.go . Fetcher
+
+// Fetcher fetches a url, sharing the url policy and fetch options of the
+// annotation reconciler.
+type Fetcher interface {
+	FetchURL(ctx context.Context, rawURL string, header http.Header) (string, error)
+}
+
+// RemoteDataReconciler fetches the sources of a RemoteData that are due into
+// the ConfigMap or Secret it targets, recording the outcome in its status.
+type RemoteDataReconciler struct {
+	clientset kubernetes.Interface
+	client    versioned.Interface
+	lister    listers.RemoteDataLister
+	fetcher   Fetcher
+	redactor  *redact.Redactor
+	now       func() time.Time
+}
+
+func NewReconciler(clientset kubernetes.Interface, client versioned.Interface, lister listers.RemoteDataLister, fetcher Fetcher, redactor *redact.Redactor) *RemoteDataReconciler {
+	return &RemoteDataReconciler{
+		clientset: clientset,
+		client:    client,
+		lister:    lister,
+		fetcher:   fetcher,
+		redactor:  redactor,
+		now:       time.Now,
+	}
+}
+
+// Reconcile reconciles the RemoteData with the namespace/name key, requeueing
+// it once one of its sources is next due to be refreshed. Nothing is written
+// once ctx is done.
+func (r *RemoteDataReconciler) Reconcile(ctx context.Context, key string) (controller.Result, error) {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return controller.Result{}, err
+	}
+
+	cached, err := r.lister.RemoteDatas(namespace).Get(name)
+	if apierrors.IsNotFound(err) {
+		return controller.Result{}, nil
+	}
+	if err != nil {
+		return controller.Result{}, err
+	}
+	remoteData := cached.DeepCopy()
+	logger := log.With(log.Fields{"namespace": namespace, "name": name, "kind": "RemoteData"})
+
+	if err := Validate(remoteData.Spec); err != nil {
+		r.setReady(remoteData, metav1.ConditionFalse, ReasonInvalid, err.Error())
+		logger.With(log.Fields{"error": err}).Error("invalid remotedata")
+		return controller.Result{}, r.updateStatus(ctx, cached, remoteData)
+	}
+
+	existing, current, err := r.target(remoteData)
+	if err != nil {
+		r.setReady(remoteData, metav1.ConditionFalse, ReasonTargetConflict, err.Error())
+		if statusErr := r.updateStatus(ctx, cached, remoteData); statusErr != nil {
+			return controller.Result{}, statusErr
+		}
+		return controller.Result{}, err
+	}
+
+	specChanged := remoteData.Status.ObservedGeneration != remoteData.Generation
+	tampered := existing != nil && annotation(existing, ChecksumAnnotation) != Checksum(current)
+	now := r.now()
+	data := map[string]string{}
+	var (
+		statuses []v1alpha1.SourceStatus
+		failed   []string
+		next     time.Duration
+	)
+	for _, source := range remoteData.Spec.Sources {
+		status := findSourceStatus(remoteData.Status.Sources, source.Key)
+		value, ok := current[source.Key]
+		if ok {
+			data[source.Key] = value
+		}
+
+		if specChanged || tampered || !ok || due(source, status, now) {
+			value, err := r.fetch(ctx, remoteData.Namespace, source)
+			if err != nil {
+				setCondition(&status.Conditions, v1alpha1.ConditionFetched, metav1.ConditionFalse, ReasonFetchFailed, r.redactor.String(err.Error()), now)
+				failed = append(failed, fmt.Sprintf("%s: %v", source.Key, err))
+			} else {
+				data[source.Key] = value
+				fetchTime := metav1.NewTime(now)
+				status.LastFetchTime = &fetchTime
+				setCondition(&status.Conditions, v1alpha1.ConditionFetched, metav1.ConditionTrue, ReasonFetched, "", now)
+			}
+		}
+
+		if source.RefreshInterval != nil && source.RefreshInterval.Duration > 0 && status.LastFetchTime != nil {
+			wait := status.LastFetchTime.Add(source.RefreshInterval.Duration).Sub(now)
+			if wait <= 0 {
+				wait = source.RefreshInterval.Duration
+			}
+			if next == 0 || wait < next {
+				next = wait
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	remoteData.Status.Sources = statuses
+
+	if existing == nil || tampered || !equality.Semantic.DeepEqual(current, data) || annotation(existing, ChecksumAnnotation) != Checksum(data) {
+		if err := r.writeTarget(ctx, remoteData, existing, data); err != nil {
+			r.setReady(remoteData, metav1.ConditionFalse, ReasonWriteFailed, err.Error())
+			if statusErr := r.updateStatus(ctx, cached, remoteData); statusErr != nil {
+				return controller.Result{}, statusErr
+			}
+			return controller.Result{}, err
+		}
+		logger.Debug("updated %s %s", remoteData.Spec.Target.Kind, remoteData.Spec.Target.Name)
+	}
+
+	if len(failed) > 0 {
+		r.setReady(remoteData, metav1.ConditionFalse, ReasonFetchFailed, strings.Join(failed, "; "))
+	} else {
+		r.setReady(remoteData, metav1.ConditionTrue, ReasonFetched, "")
+	}
+	remoteData.Status.ObservedGeneration = remoteData.Generation
+	if err := r.updateStatus(ctx, cached, remoteData); err != nil {
+		return controller.Result{}, err
+	}
+
+	result := controller.Result{RequeueAfter: next}
+	if len(failed) > 0 {
+		return result, fmt.Errorf("failed to fetch %s", strings.Join(failed, "; "))
+	}
+	return result, nil
+}
+
+// Validate checks the parts of a spec the CRD schema can't.
+func Validate(spec v1alpha1.RemoteDataSpec) error {
+	switch spec.Target.Kind {
+	case v1alpha1.TargetConfigMap, v1alpha1.TargetSecret:
+	default:
+		return fmt.Errorf("unknown target kind '%s', expected %s or %s", spec.Target.Kind, v1alpha1.TargetConfigMap, v1alpha1.TargetSecret)
+	}
+	if errs := validation.IsDNS1123Subdomain(spec.Target.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid target name '%s': %s", spec.Target.Name, strings.Join(errs, ", "))
+	}
+
+	keys := map[string]bool{}
+	for _, source := range spec.Sources {
+		if errs := validation.IsConfigMapKey(source.Key); len(errs) > 0 {
+			return fmt.Errorf("invalid data key '%s': %s", source.Key, strings.Join(errs, ", "))
+		}
+		if keys[source.Key] {
+			return fmt.Errorf("data key '%s' is used by more than one source", source.Key)
+		}
+		keys[source.Key] = true
+
+		for _, header := range source.Headers {
+			if (header.Value != "") == (header.ValueFrom != nil) {
+				return fmt.Errorf("header '%s' for data key '%s' needs exactly one of value and valueFrom", header.Name, source.Key)
+			}
+			if header.ValueFrom != nil && (header.ValueFrom.SecretKeyRef == nil || header.ValueFrom.SecretKeyRef.Name == "" || header.ValueFrom.SecretKeyRef.Key == "") {
+				return fmt.Errorf("header '%s' for data key '%s' needs the name and key of a secret in valueFrom.secretKeyRef", header.Name, source.Key)
+			}
+		}
+
+		for _, transform := range source.Transforms {
+			switch transform.Type {
+			case v1alpha1.TransformTrimSpace, v1alpha1.TransformBase64Decode:
+			case v1alpha1.TransformJSONPath:
+				if err := jsonpath.New(source.Key).Parse(transform.Expression); err != nil {
+					return fmt.Errorf("invalid jsonpath expression '%s' for data key '%s': %v", transform.Expression, source.Key, err)
+				}
+			default:
+				return fmt.Errorf("unknown transform type '%s' for data key '%s', expected %s, %s or %s", transform.Type, source.Key,
+					v1alpha1.TransformTrimSpace, v1alpha1.TransformBase64Decode, v1alpha1.TransformJSONPath)
+			}
+		}
+	}
+	return nil
+}
+
+func due(source v1alpha1.Source, status v1alpha1.SourceStatus, now time.Time) bool {
+	if status.LastFetchTime == nil {
+		return true
+	}
+	if source.RefreshInterval == nil || source.RefreshInterval.Duration <= 0 {
+		return false
+	}
+	return !now.Before(status.LastFetchTime.Add(source.RefreshInterval.Duration))
+}
+
+// fetch fetches source for a RemoteData in namespace. Header values read from
+// secrets are marked sensitive so fetchers don't log them.
+func (r *RemoteDataReconciler) fetch(ctx context.Context, namespace string, source v1alpha1.Source) (string, error) {
+	ctx = reconciler.WithNamespace(ctx, namespace)
+	header := http.Header{}
+	for _, h := range source.Headers {
+		if h.ValueFrom != nil {
+			ctx = reconciler.WithSensitiveHeaders(ctx, h.Name)
+		}
+		value, ok, err := r.headerValue(namespace, h)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			header.Add(h.Name, value)
+		}
+	}
+
+	value, err := r.fetcher.FetchURL(ctx, source.URL, header)
+	if err != nil {
+		return "", err
+	}
+	return transform(value, source.Transforms)
+}
+
+// headerValue returns the value of h, reading it from a secret in namespace
+// for valueFrom. An optional secret or key that doesn't exist leaves the header
+// out.
+func (r *RemoteDataReconciler) headerValue(namespace string, h v1alpha1.Header) (string, bool, error) {
+	if h.ValueFrom == nil {
+		return h.Value, true, nil
+	}
+	ref := h.ValueFrom.SecretKeyRef
+	optional := ref.Optional != nil && *ref.Optional
+
+	secret, err := r.clientset.CoreV1().Secrets(namespace).Get(ref.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) && optional {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get secret %s for header %s: %v", ref.Name, h.Name, err)
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		if optional {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("secret %s has no key %s for header %s", ref.Name, ref.Key, h.Name)
+	}
+	return string(value), true, nil
+}
+
+func transform(value string, transforms []v1alpha1.Transform) (string, error) {
+	for _, t := range transforms {
+		switch t.Type {
+		case v1alpha1.TransformTrimSpace:
+			value = strings.TrimSpace(value)
+		case v1alpha1.TransformBase64Decode:
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+			if err != nil {
+				return "", fmt.Errorf("failed to base64 decode response: %v", err)
+			}
+			value = string(decoded)
+		case v1alpha1.TransformJSONPath:
+			var document interface{}
+			if err := json.Unmarshal([]byte(value), &document); err != nil {
+				return "", fmt.Errorf("failed to decode response as json: %v", err)
+			}
+			path := jsonpath.New("transform")
+			if err := path.Parse(t.Expression); err != nil {
+				return "", err
+			}
+			out := new(bytes.Buffer)
+			if err := path.Execute(out, document); err != nil {
+				return "", fmt.Errorf("failed to apply jsonpath %s: %v", t.Expression, err)
+			}
+			value = out.String()
+		}
+	}
+	return value, nil
+}
+
+// target returns the target and its data, nil when it doesn't exist, or an
+// error if it exists but isn't controlled by remoteData.
+func (r *RemoteDataReconciler) target(remoteData *v1alpha1.RemoteData) (runtime.Object, map[string]string, error) {
+	var (
+		object runtime.Object
+		data   = map[string]string{}
+		err    error
+	)
+	switch remoteData.Spec.Target.Kind {
+	case v1alpha1.TargetSecret:
+		var secret *apiv1.Secret
+		secret, err = r.clientset.CoreV1().Secrets(remoteData.Namespace).Get(remoteData.Spec.Target.Name, metav1.GetOptions{})
+		if err == nil {
+			object = secret
+			for key, value := range secret.Data {
+				data[key] = string(value)
+			}
+		}
+	default:
+		var configMap *apiv1.ConfigMap
+		configMap, err = r.clientset.CoreV1().ConfigMaps(remoteData.Namespace).Get(remoteData.Spec.Target.Name, metav1.GetOptions{})
+		if err == nil {
+			object = configMap
+			for key, value := range configMap.Data {
+				data[key] = value
+			}
+		}
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get target: %v", err)
+	}
+
+	if !metav1.IsControlledBy(object.(metav1.Object), remoteData) {
+		return nil, nil, fmt.Errorf("%s %s already exists and is not owned by this RemoteData", remoteData.Spec.Target.Kind, remoteData.Spec.Target.Name)
+	}
+	return object, data, nil
+}
+
+// writeTarget sets the data of existing, creating the target owned by
+// remoteData when existing is nil. Keys not in data are removed. client-go
+// calls don't take a context yet, so nothing is written once ctx is done.
+func (r *RemoteDataReconciler) writeTarget(ctx context.Context, remoteData *v1alpha1.RemoteData, existing runtime.Object, data map[string]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	meta := metav1.ObjectMeta{
+		Name:        remoteData.Spec.Target.Name,
+		Namespace:   remoteData.Namespace,
+		Labels:      map[string]string{TargetLabel: "true"},
+		Annotations: map[string]string{ChecksumAnnotation: Checksum(data)},
+		OwnerReferences: []metav1.OwnerReference{
+			*metav1.NewControllerRef(remoteData, v1alpha1.SchemeGroupVersion.WithKind("RemoteData")),
+		},
+	}
+
+	var err error
+	switch target := existing.(type) {
+	case *apiv1.Secret:
+		secret := target.DeepCopy()
+		mark(&secret.ObjectMeta, meta)
+		secret.Data = secretData(data)
+		_, err = r.clientset.CoreV1().Secrets(meta.Namespace).Update(secret)
+	case *apiv1.ConfigMap:
+		configMap := target.DeepCopy()
+		mark(&configMap.ObjectMeta, meta)
+		configMap.Data = data
+		_, err = r.clientset.CoreV1().ConfigMaps(meta.Namespace).Update(configMap)
+	default:
+		if remoteData.Spec.Target.Kind == v1alpha1.TargetSecret {
+			secret := &apiv1.Secret{ObjectMeta: meta, Type: apiv1.SecretTypeOpaque, Data: secretData(data)}
+			_, err = r.clientset.CoreV1().Secrets(meta.Namespace).Create(secret)
+		} else {
+			configMap := &apiv1.ConfigMap{ObjectMeta: meta, Data: data}
+			_, err = r.clientset.CoreV1().ConfigMaps(meta.Namespace).Create(configMap)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s %s: %v", remoteData.Spec.Target.Kind, meta.Name, err)
+	}
+	return nil
+}
+
+// mark copies the label and checksum of meta onto target.
+func mark(target *metav1.ObjectMeta, meta metav1.ObjectMeta) {
+	if target.Labels == nil {
+		target.Labels = map[string]string{}
+	}
+	if target.Annotations == nil {
+		target.Annotations = map[string]string{}
+	}
+	target.Labels[TargetLabel] = meta.Labels[TargetLabel]
+	target.Annotations[ChecksumAnnotation] = meta.Annotations[ChecksumAnnotation]
+}
+
+func annotation(object runtime.Object, key string) string {
+	return object.(metav1.Object).GetAnnotations()[key]
+}
+
+// Checksum returns the sha256 of data, independent of the order of its keys.
+func Checksum(data map[string]string) string {
+	encoded, _ := json.Marshal(data)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+func secretData(data map[string]string) map[string][]byte {
+	secret := map[string][]byte{}
+	for key, value := range data {
+		secret[key] = []byte(value)
+	}
+	return secret
+}
+
+// setReady sets the Ready condition. Its message is redacted as the status
+// can be read by anyone who can read the RemoteData.
+func (r *RemoteDataReconciler) setReady(remoteData *v1alpha1.RemoteData, status metav1.ConditionStatus, reason, message string) {
+	setCondition(&remoteData.Status.Conditions, v1alpha1.ConditionReady, status, reason, r.redactor.String(message), r.now())
+}
+
+// updateStatus writes the status of remoteData if it changed from cached and
+// ctx isn't done.
+func (r *RemoteDataReconciler) updateStatus(ctx context.Context, cached, remoteData *v1alpha1.RemoteData) error {
+	if equality.Semantic.DeepEqual(cached.Status, remoteData.Status) {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := r.client.CurlmeV1alpha1().RemoteDatas(remoteData.Namespace).UpdateStatus(remoteData)
+	if err != nil {
+		return fmt.Errorf("failed to update status: %v", err)
+	}
+	return nil
+}
+
+func findSourceStatus(statuses []v1alpha1.SourceStatus, key string) v1alpha1.SourceStatus {
+	for _, status := range statuses {
+		if status.Key == key {
+			return status
+		}
+	}
+	return v1alpha1.SourceStatus{Key: key}
+}
+
+// setCondition sets a condition, only moving its transition time when the
+// status changes.
+func setCondition(conditions *[]v1alpha1.Condition, conditionType string, status metav1.ConditionStatus, reason, message string, now time.Time) {
+	for i := range *conditions {
+		condition := &(*conditions)[i]
+		if condition.Type != conditionType {
+			continue
+		}
+		if condition.Status != status {
+			condition.LastTransitionTime = metav1.NewTime(now)
+		}
+		condition.Status = status
+		condition.Reason = reason
+		condition.Message = message
+		return
+	}
+	*conditions = append(*conditions, v1alpha1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.NewTime(now),
+	})
+}
@@ -0,0 +1,13 @@
+package remotedata_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRemoteData(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RemoteData Suite")
+}
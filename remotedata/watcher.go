@@ -0,0 +1,94 @@
+package remotedata
+
+import (
+	"strings"
+
+	"github.com/aclevername/config-map-controller/apis/curlme/v1alpha1"
+	"github.com/aclevername/config-map-controller/log"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TargetWatcher watches the metadata of the ConfigMaps and Secrets carrying
+// TargetLabel, passing the key of the RemoteData controlling one to enqueue
+// whenever it is changed or deleted, so the target is restored.
+type TargetWatcher struct {
+	enqueue   func(key string)
+	informers []cache.Controller
+}
+
+func NewTargetWatcher(metadataClient metadata.Interface, enqueue func(key string)) *TargetWatcher {
+	w := &TargetWatcher{enqueue: enqueue}
+	for _, resource := range []string{"configmaps", "secrets"} {
+		targets := metadataClient.Resource(apiv1.SchemeGroupVersion.WithResource(resource)).Namespace(apiv1.NamespaceAll)
+		_, informer := cache.NewInformer(
+			&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					options.LabelSelector = TargetLabel
+					return targets.List(options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					options.LabelSelector = TargetLabel
+					return targets.Watch(options)
+				},
+			},
+			&metav1.PartialObjectMetadata{},
+			0,
+			cache.ResourceEventHandlerFuncs{
+				UpdateFunc: func(_, obj interface{}) {
+					w.changed(obj)
+				},
+				DeleteFunc: w.changed,
+			},
+		)
+		w.informers = append(w.informers, informer)
+	}
+	return w
+}
+
+func (w *TargetWatcher) changed(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	owner := metav1.GetControllerOf(accessor)
+	if owner == nil || owner.Kind != "RemoteData" || !strings.HasPrefix(owner.APIVersion, v1alpha1.GroupName+"/") {
+		return
+	}
+	key := accessor.GetNamespace() + "/" + owner.Name
+	log.Debug("target %s/%s of RemoteData %s changed, requeuing it", accessor.GetNamespace(), accessor.GetName(), key)
+	w.enqueue(key)
+}
+
+func (w *TargetWatcher) Run(stopCh <-chan struct{}) {
+	for _, informer := range w.informers {
+		go informer.Run(stopCh)
+	}
+	<-stopCh
+}
+
+func (w *TargetWatcher) HasSynced() bool {
+	for _, informer := range w.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *TargetWatcher) LastSyncResourceVersion() string {
+	var versions []string
+	for _, informer := range w.informers {
+		versions = append(versions, informer.LastSyncResourceVersion())
+	}
+	return strings.Join(versions, ",")
+}
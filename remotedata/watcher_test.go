@@ -0,0 +1,80 @@
+package remotedata_test
+
+import (
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/apis/curlme/v1alpha1"
+	"github.com/aclevername/config-map-controller/remotedata"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metadatafake "k8s.io/client-go/metadata/fake"
+)
+
+var _ = Describe("TargetWatcher", func() {
+	var (
+		metadataClient *metadatafake.FakeMetadataClient
+		stopCh         chan struct{}
+		mu             sync.Mutex
+		enqueued       []string
+	)
+
+	enqueuedKeys := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, enqueued...)
+	}
+
+	targetMetadata := func(kind, name string, owner *metav1.OwnerReference) *metav1.PartialObjectMetadata {
+		object := &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: kind},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a", Labels: map[string]string{remotedata.TargetLabel: "true"}},
+		}
+		if owner != nil {
+			object.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		return object
+	}
+
+	BeforeEach(func() {
+		enqueued = nil
+		owner := &v1alpha1.RemoteData{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "jokes", UID: "remote-data-id"}}
+		controllerRef := metav1.NewControllerRef(owner, v1alpha1.SchemeGroupVersion.WithKind("RemoteData"))
+
+		scheme := runtime.NewScheme()
+		metav1.AddMetaToScheme(scheme)
+		metadataClient = metadatafake.NewSimpleMetadataClient(scheme,
+			targetMetadata("ConfigMap", "jokes", controllerRef),
+			targetMetadata("Secret", "token", controllerRef),
+			targetMetadata("ConfigMap", "unowned", nil),
+		)
+
+		watcher := remotedata.NewTargetWatcher(metadataClient, func(key string) {
+			mu.Lock()
+			defer mu.Unlock()
+			enqueued = append(enqueued, key)
+		})
+		stopCh = make(chan struct{})
+		go watcher.Run(stopCh)
+		Eventually(watcher.HasSynced).Should(BeTrue())
+	})
+
+	AfterEach(func() {
+		close(stopCh)
+	})
+
+	It("requeues the RemoteData controlling a target when it is deleted", func() {
+		Consistently(enqueuedKeys).Should(BeEmpty())
+
+		configMaps := metadataClient.Resource(apiv1.SchemeGroupVersion.WithResource("configmaps")).Namespace("team-a")
+		Expect(configMaps.Delete("jokes", nil)).To(Succeed())
+		Eventually(enqueuedKeys).Should(ConsistOf("team-a/jokes"))
+
+		Expect(configMaps.Delete("unowned", nil)).To(Succeed())
+		Consistently(enqueuedKeys).Should(ConsistOf("team-a/jokes"))
+	})
+})
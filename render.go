@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/aclevername/config-map-controller/config"
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/metrics"
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/redact"
+	renderer "github.com/aclevername/config-map-controller/render"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// render reconciles the ConfigMaps in a manifest against a fake clientset
+// and prints the result, returning the exit code.
+func render(args []string) int {
+	flags := flag.NewFlagSet("render", flag.ExitOnError)
+	file := flags.String("f", "", "manifest holding the configmaps to render, - for stdin")
+	diff := flags.Bool("diff", false, "print a diff against the input instead of the rendered configmaps")
+	offline := flags.Bool("offline", false, "answer fetches from -responses instead of the network")
+	responsesFile := flags.String("responses", "", "yaml file of recorded responses keyed by url, used by -offline")
+	recordFile := flags.String("record", "", "write the responses fetched to this file for later use with -offline")
+	configFile := flags.String("config", "", "path to a ControllerConfig file to take the annotation key, policy and http settings from, replacing the policy flags")
+	policyFromFlags := policyFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s render -f manifest [flags]\n\nRuns a reconcile against the ConfigMaps in the manifest without a cluster, printing the result and any events.\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if *file == "" || (*offline && *responsesFile == "") || (*offline && *recordFile != "") {
+		flags.Usage()
+		return 1
+	}
+
+	cfg, err := loadConfig(flags, *configFile, func() config.Config {
+		cfg := config.Default()
+		cfg.Policy = policyFromFlags()
+		return cfg
+	})
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			log.Error("%v", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	configMaps, err := renderer.Load(in)
+	if err != nil {
+		log.Error("failed to decode %s: %v", *file, err)
+		return 1
+	}
+	if len(configMaps) == 0 {
+		log.Error("no configmaps found in %s", *file)
+		return 1
+	}
+
+	var httpClient reconciler.HTTPClient = &http.Client{}
+	var recorder *renderer.Recorder
+	if *offline {
+		responses, err := renderer.LoadResponses(*responsesFile)
+		if err != nil {
+			log.Error("%v", err)
+			return 1
+		}
+		httpClient = &renderer.Replay{Responses: responses}
+	} else if *recordFile != "" {
+		recorder = &renderer.Recorder{Client: httpClient}
+		httpClient = recorder
+	}
+
+	redactor := redact.New(redact.DefaultQueryParams, redact.DefaultHeaders)
+	metricsRecorder := metrics.New()
+	newReconciler := func(clientset kubernetes.Interface) renderer.Reconciler {
+		r := reconciler.New(clientset, cfg.Annotation.Key, metricsRecorder, redactor, cfg.Policy)
+		r.SetFetchOptions(cfg.FetchOptions())
+		r.SetHTTPClient(httpClient)
+		return &r
+	}
+
+	exitCode := 0
+	for i, configMap := range configMaps {
+		result, err := renderer.Run(context.Background(), configMap, newReconciler)
+		if err != nil {
+			log.Error("failed to render configmap %s: %v", configMap.Name, err)
+			exitCode = 1
+			continue
+		}
+
+		for _, event := range result.Events {
+			fmt.Fprintf(os.Stderr, "%s event for configmap %s: %s\n", event.Type, configMap.Name, event.Message)
+		}
+		if result.Err != nil {
+			exitCode = 1
+		}
+
+		var output []byte
+		if *diff {
+			var d string
+			d, err = renderer.Diff(result)
+			output = []byte(d)
+		} else {
+			output, err = renderer.YAML(result.Output)
+		}
+		if err != nil {
+			log.Error("failed to print configmap %s: %v", configMap.Name, err)
+			exitCode = 1
+			continue
+		}
+
+		if i > 0 && !*diff {
+			fmt.Println("---")
+		}
+		_, _ = os.Stdout.Write(output)
+	}
+
+	if recorder != nil {
+		if err := recorder.Responses.Save(*recordFile); err != nil {
+			log.Error("failed to save responses: %v", err)
+			return 1
+		}
+	}
+	return exitCode
+}
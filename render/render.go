@@ -0,0 +1,114 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pmezard/go-difflib/difflib"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+type Reconciler interface {
+	ReconcileResource(ctx context.Context, cm *apiv1.ConfigMap) error
+}
+
+// Result is the outcome of reconciling a ConfigMap locally.
+type Result struct {
+	Input  *apiv1.ConfigMap
+	Output *apiv1.ConfigMap
+	Events []apiv1.Event
+	Err    error
+}
+
+// Load decodes the ConfigMaps in a yaml or json stream, skipping documents of
+// other kinds.
+func Load(r io.Reader) ([]*apiv1.ConfigMap, error) {
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	var configMaps []*apiv1.ConfigMap
+	for {
+		var configMap apiv1.ConfigMap
+		err := decoder.Decode(&configMap)
+		if err == io.EOF {
+			return configMaps, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if configMap.APIVersion != "v1" || configMap.Kind != "ConfigMap" {
+			continue
+		}
+		configMaps = append(configMaps, &configMap)
+	}
+}
+
+// Run reconciles configMap against a fake clientset, using newReconciler to
+// build the reconciler for it, and returns the ConfigMap and events the
+// reconcile left behind. ConfigMaps without a namespace are reconciled in
+// the default namespace.
+func Run(ctx context.Context, configMap *apiv1.ConfigMap, newReconciler func(kubernetes.Interface) Reconciler) (Result, error) {
+	if configMap.Name == "" {
+		return Result{}, errors.New("configmap has no name")
+	}
+
+	working := configMap.DeepCopy()
+	if working.Namespace == "" {
+		working.Namespace = apiv1.NamespaceDefault
+	}
+
+	clientset := fake.NewSimpleClientset(working)
+	reconcileErr := newReconciler(clientset).ReconcileResource(ctx, working)
+
+	output, err := clientset.CoreV1().ConfigMaps(working.Namespace).Get(working.Name, metav1.GetOptions{})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get rendered configmap: %v", err)
+	}
+	output.Namespace = configMap.Namespace
+
+	events, err := clientset.CoreV1().Events(working.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to list events: %v", err)
+	}
+
+	return Result{
+		Input:  configMap.DeepCopy(),
+		Output: output,
+		Events: events.Items,
+		Err:    reconcileErr,
+	}, nil
+}
+
+func YAML(configMap *apiv1.ConfigMap) ([]byte, error) {
+	withType := configMap.DeepCopy()
+	withType.APIVersion = "v1"
+	withType.Kind = "ConfigMap"
+	return yaml.Marshal(withType)
+}
+
+// Diff returns a unified diff of the rendered ConfigMap against the input,
+// empty when nothing changed.
+func Diff(result Result) (string, error) {
+	input, err := YAML(result.Input)
+	if err != nil {
+		return "", err
+	}
+	output, err := YAML(result.Output)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(input)),
+		B:        difflib.SplitLines(string(output)),
+		FromFile: "input",
+		ToFile:   "rendered",
+		Context:  3,
+	})
+}
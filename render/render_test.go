@@ -0,0 +1,117 @@
+package render_test
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/metrics"
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/redact"
+	"github.com/aclevername/config-map-controller/render"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+var _ = Describe("Run", func() {
+	var (
+		configMap     *apiv1.ConfigMap
+		replay        *render.Replay
+		newReconciler func(kubernetes.Interface) render.Reconciler
+	)
+
+	BeforeEach(func() {
+		configMap = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "valid",
+				Annotations: map[string]string{"x-k8s.io/curl-me-that": "mydata=curl-a-joke.herokuapp.com"},
+			},
+			Data: map[string]string{"hello": "world"},
+		}
+		replay = &render.Replay{Responses: render.Responses{
+			"https://curl-a-joke.herokuapp.com": {StatusCode: 200, Body: "a joke"},
+		}}
+		newReconciler = func(clientset kubernetes.Interface) render.Reconciler {
+			r := reconciler.New(clientset, "x-k8s.io/curl-me-that", metrics.New(), redact.New(nil, nil), reconciler.Policy{})
+			r.SetHTTPClient(replay)
+			return &r
+		}
+	})
+
+	It("returns the reconciled configmap", func() {
+		result, err := render.Run(context.Background(), configMap, newReconciler)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Err).NotTo(HaveOccurred())
+		Expect(result.Events).To(BeEmpty())
+		Expect(result.Input).To(Equal(configMap))
+		Expect(result.Output.Namespace).To(BeEmpty())
+		Expect(result.Output.Data).To(Equal(map[string]string{"hello": "world", "mydata": "a joke"}))
+	})
+
+	It("returns the events and error of a failed reconcile", func() {
+		replay.Responses = render.Responses{}
+		result, err := render.Run(context.Background(), configMap, newReconciler)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Err).To(MatchError(ContainSubstring("no recorded response for https://curl-a-joke.herokuapp.com")))
+		Expect(result.Events).To(HaveLen(1))
+		Expect(result.Events[0].Message).To(ContainSubstring("no recorded response for https://curl-a-joke.herokuapp.com"))
+		Expect(result.Output.Data).To(Equal(configMap.Data))
+	})
+
+	It("requires a name", func() {
+		configMap.Name = ""
+		_, err := render.Run(context.Background(), configMap, newReconciler)
+		Expect(err).To(MatchError("configmap has no name"))
+	})
+
+	It("diffs the rendered configmap against the input", func() {
+		result, err := render.Run(context.Background(), configMap, newReconciler)
+		Expect(err).NotTo(HaveOccurred())
+
+		diff, err := render.Diff(result)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff).To(HavePrefix("--- input\n+++ rendered\n"))
+		Expect(diff).To(ContainSubstring("\n+  mydata: a joke\n"))
+		Expect(strings.Count(diff, "\n+")).To(Equal(2))
+	})
+
+	It("returns an empty diff when nothing changed", func() {
+		configMap.Data["mydata"] = "already set"
+		result, err := render.Run(context.Background(), configMap, newReconciler)
+		Expect(err).NotTo(HaveOccurred())
+
+		diff, err := render.Diff(result)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Load", func() {
+	It("decodes the configmaps in a stream, skipping other kinds", func() {
+		configMaps, err := render.Load(strings.NewReader(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: skipped
+---
+{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "second"}}
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configMaps).To(HaveLen(2))
+		Expect(configMaps[0].Name).To(Equal("first"))
+		Expect(configMaps[1].Name).To(Equal("second"))
+	})
+
+	It("returns decoding errors", func() {
+		_, err := render.Load(strings.NewReader("kind: [\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,95 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Response is a recorded http response.
+type Response struct {
+	StatusCode int    `json:"status"`
+	Body       string `json:"body"`
+}
+
+// Responses are recorded responses keyed by url.
+type Responses map[string]Response
+
+func LoadResponses(file string) (Responses, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := Responses{}
+	if err := yaml.UnmarshalStrict(data, &responses); err != nil {
+		return nil, fmt.Errorf("failed to decode responses from %s: %v", file, err)
+	}
+	return responses, nil
+}
+
+func (r Responses) Save(file string) error {
+	data, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// Replay answers requests from recorded responses, failing requests for urls
+// that weren't recorded.
+type Replay struct {
+	Responses Responses
+}
+
+func (r *Replay) Do(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	response, ok := r.Responses[url]
+	if !ok {
+		return nil, fmt.Errorf("no recorded response for %s", url)
+	}
+	return &http.Response{
+		StatusCode: response.StatusCode,
+		Body:       ioutil.NopCloser(bytes.NewBufferString(response.Body)),
+	}, nil
+}
+
+// Recorder records the responses of the requests it passes to Client.
+type Recorder struct {
+	Client    reconciler.HTTPClient
+	Responses Responses
+
+	mu sync.Mutex
+}
+
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var body []byte
+	if resp.Body != nil {
+		body, err = ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	if r.Responses == nil {
+		r.Responses = Responses{}
+	}
+	r.Responses[req.URL.String()] = Response{StatusCode: resp.StatusCode, Body: string(body)}
+	r.mu.Unlock()
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
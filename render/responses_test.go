@@ -0,0 +1,89 @@
+package render_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/reconciler/fakes"
+	"github.com/aclevername/config-map-controller/render"
+)
+
+var _ = Describe("Responses", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "responses")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("saves and loads recorded responses", func() {
+		file := filepath.Join(dir, "responses.yml")
+		responses := render.Responses{
+			"https://example.com/a": {StatusCode: 200, Body: "a"},
+			"https://example.com/b": {StatusCode: 404},
+		}
+		Expect(responses.Save(file)).To(Succeed())
+
+		loaded, err := render.LoadResponses(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(responses))
+	})
+
+	It("rejects files that aren't responses", func() {
+		file := filepath.Join(dir, "responses.yml")
+		Expect(ioutil.WriteFile(file, []byte("https://example.com:\n  code: 200\n"), 0644)).To(Succeed())
+
+		_, err := render.LoadResponses(file)
+		Expect(err).To(MatchError(ContainSubstring("failed to decode responses from")))
+	})
+})
+
+var _ = Describe("Replay", func() {
+	It("answers recorded urls and fails others", func() {
+		replay := &render.Replay{Responses: render.Responses{
+			"https://example.com/a": {StatusCode: 200, Body: "a"},
+		}}
+
+		req, _ := http.NewRequest("GET", "https://example.com/a", nil)
+		resp, err := replay.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(200))
+		Expect(ioutil.ReadAll(resp.Body)).To(Equal([]byte("a")))
+
+		req, _ = http.NewRequest("GET", "https://example.com/b", nil)
+		_, err = replay.Do(req)
+		Expect(err).To(MatchError("no recorded response for https://example.com/b"))
+	})
+})
+
+var _ = Describe("Recorder", func() {
+	It("records responses while passing them on", func() {
+		fakeHTTPClient := new(fakes.FakeHTTPClient)
+		fakeHTTPClient.DoReturns(&http.Response{StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("a"))}, nil)
+		recorder := &render.Recorder{Client: fakeHTTPClient}
+
+		req, _ := http.NewRequest("GET", "https://example.com/a", nil)
+		resp, err := recorder.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.ReadAll(resp.Body)).To(Equal([]byte("a")))
+		Expect(recorder.Responses).To(Equal(render.Responses{
+			"https://example.com/a": {StatusCode: 200, Body: "a"},
+		}))
+
+		fakeHTTPClient.DoReturns(nil, errors.New("failed"))
+		_, err = recorder.Do(req)
+		Expect(err).To(MatchError("failed"))
+	})
+})
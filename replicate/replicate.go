@@ -0,0 +1,406 @@
+package replicate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aclevername/config-map-controller/log"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ToAnnotation on a ConfigMap lists the namespaces, separated by commas,
+	// to keep a copy of it in. Wildcards such as team-* are supported.
+	ToAnnotation = "x-k8s.io/replicate-to"
+
+	// ToSelectorAnnotation on a ConfigMap selects the namespaces to keep a
+	// copy of it in by label.
+	ToSelectorAnnotation = "x-k8s.io/replicate-to-selector"
+
+	// SourceLabel marks a replica with a hash of the namespace and name of its
+	// source, so the replicas of a source can be listed. SourceAnnotation
+	// holds them in full.
+	SourceLabel      = "x-k8s.io/replica-of"
+	SourceAnnotation = "x-k8s.io/replica-of"
+
+	// IndexName indexes the ConfigMaps to replicate by namespace.
+	IndexName = "replicate"
+
+	// sourceIndexName indexes replicas by the namespace/name of their source.
+	sourceIndexName = "source"
+)
+
+// IndexFunc indexes ConfigMaps with either replicate annotation by their
+// namespace, for use with IndexName.
+func IndexFunc(obj interface{}) ([]string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, nil
+	}
+	if !IsSource(accessor.GetAnnotations()) {
+		return nil, nil
+	}
+	return []string{accessor.GetNamespace()}, nil
+}
+
+// IsSource reports whether annotations ask for the ConfigMap to be replicated.
+func IsSource(annotations map[string]string) bool {
+	_, to := annotations[ToAnnotation]
+	_, selector := annotations[ToSelectorAnnotation]
+	return to || selector
+}
+
+// Sources looks up the ConfigMaps to replicate in an index built with
+// IndexFunc.
+type Sources interface {
+	ByIndex(indexName, value string) ([]interface{}, error)
+}
+
+// Replicator keeps copies of the data of ConfigMaps in the namespaces their
+// annotations select. Only ConfigMaps in the namespaces it replicates from are
+// replicated. It watches namespaces, passing the keys of the ConfigMaps to
+// replicate to enqueue whenever one changes, so copies follow namespaces that
+// start or stop matching. It also watches the copies, passing the key of their
+// source to enqueue when one is changed or deleted, so they are restored.
+type Replicator struct {
+	clientset         kubernetes.Interface
+	from              []string
+	sources           Sources
+	enqueue           func(key string)
+	namespaces        cache.Store
+	namespaceInformer cache.Controller
+	replicas          cache.Indexer
+	replicaInformer   cache.Controller
+}
+
+func New(clientset kubernetes.Interface, from []string, sources Sources, enqueue func(key string)) *Replicator {
+	r := &Replicator{clientset: clientset, from: from, sources: sources, enqueue: enqueue}
+	r.namespaces, r.namespaceInformer = cache.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return clientset.CoreV1().Namespaces().List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return clientset.CoreV1().Namespaces().Watch(options)
+			},
+		},
+		&apiv1.Namespace{},
+		0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				r.changed()
+			},
+			UpdateFunc: func(old, obj interface{}) {
+				if !reflect.DeepEqual(old.(*apiv1.Namespace).Labels, obj.(*apiv1.Namespace).Labels) ||
+					old.(*apiv1.Namespace).Status.Phase != obj.(*apiv1.Namespace).Status.Phase {
+					r.changed()
+				}
+			},
+			DeleteFunc: func(obj interface{}) {
+				r.changed()
+			},
+		},
+	)
+	r.replicas, r.replicaInformer = cache.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				options.LabelSelector = SourceLabel
+				return clientset.CoreV1().ConfigMaps(apiv1.NamespaceAll).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				options.LabelSelector = SourceLabel
+				return clientset.CoreV1().ConfigMaps(apiv1.NamespaceAll).Watch(options)
+			},
+		},
+		&apiv1.ConfigMap{},
+		0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: r.replicaChanged,
+			UpdateFunc: func(old, obj interface{}) {
+				r.replicaChanged(obj)
+			},
+			DeleteFunc: r.replicaChanged,
+		},
+		cache.Indexers{sourceIndexName: func(obj interface{}) ([]string, error) {
+			accessor, err := meta.Accessor(obj)
+			if err != nil {
+				return nil, nil
+			}
+			if source, ok := accessor.GetAnnotations()[SourceAnnotation]; ok {
+				return []string{source}, nil
+			}
+			return nil, nil
+		}},
+	)
+	return r
+}
+
+// replicaChanged requeues the source of a replica, which restores the replica
+// if it was edited or deleted and removes it if its source is gone.
+func (r *Replicator) replicaChanged(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	source, ok := accessor.GetAnnotations()[SourceAnnotation]
+	if !ok {
+		return
+	}
+	namespace, _, err := cache.SplitMetaNamespaceKey(source)
+	if err != nil || !r.replicatesFrom(namespace) {
+		return
+	}
+	r.enqueue(source)
+}
+
+// changed requeues every ConfigMap to replicate, as any of them may select a
+// namespace that was added, relabelled or deleted.
+func (r *Replicator) changed() {
+	for _, obj := range r.namespaces.List() {
+		namespace := obj.(*apiv1.Namespace)
+		if r.replicatesFrom(namespace.Name) {
+			r.enqueueFrom(namespace.Name)
+		}
+	}
+}
+
+func (r *Replicator) enqueueFrom(namespace string) {
+	sources, err := r.sources.ByIndex(IndexName, namespace)
+	if err != nil {
+		log.Error("failed to look up the configmaps to replicate from namespace %s: %v", namespace, err)
+		return
+	}
+	for _, source := range sources {
+		key, err := cache.MetaNamespaceKeyFunc(source)
+		if err != nil {
+			continue
+		}
+		r.enqueue(key)
+	}
+}
+
+func (r *Replicator) replicatesFrom(namespace string) bool {
+	return matches(r.from, namespace)
+}
+
+// Replicate creates or updates a copy of the data of source in each namespace
+// it selects, and deletes its copies in namespaces it no longer selects. A
+// ConfigMap of the same name that isn't a copy of source is left alone and
+// reported as an error.
+func (r *Replicator) Replicate(ctx context.Context, source *apiv1.ConfigMap) error {
+	if !r.replicatesFrom(source.Namespace) {
+		if IsSource(source.Annotations) {
+			return fmt.Errorf("replicating configmaps from namespace %s is not allowed", source.Namespace)
+		}
+		return nil
+	}
+
+	targets, err := r.targets(source)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, namespace := range targets {
+		if err := r.copy(ctx, source, namespace); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if err := r.prune(ctx, source.Namespace, source.Name, targets); err != nil {
+		failed = append(failed, err.Error())
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to replicate to %d namespace(s): %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Remove deletes the copies of the ConfigMap namespace/name, once it has been
+// deleted or is no longer managed. Sources without copies are skipped without
+// listing them.
+func (r *Replicator) Remove(ctx context.Context, namespace, name string) error {
+	if !r.replicatesFrom(namespace) {
+		return nil
+	}
+	replicas, err := r.replicas.ByIndex(sourceIndexName, namespace+"/"+name)
+	if err != nil || len(replicas) == 0 {
+		return err
+	}
+	return r.prune(ctx, namespace, name, nil)
+}
+
+// targets returns the namespaces source selects, in order. Its own namespace
+// and namespaces being deleted are never selected.
+func (r *Replicator) targets(source *apiv1.ConfigMap) ([]string, error) {
+	var patterns []string
+	if to, ok := source.Annotations[ToAnnotation]; ok {
+		for _, pattern := range strings.Split(to, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid %s annotation, bad pattern '%s': %v", ToAnnotation, pattern, err)
+			}
+			patterns = append(patterns, pattern)
+		}
+	}
+	var selector labels.Selector
+	if value, ok := source.Annotations[ToSelectorAnnotation]; ok {
+		var err error
+		selector, err = labels.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %v", ToSelectorAnnotation, err)
+		}
+	}
+
+	var targets []string
+	for _, obj := range r.namespaces.List() {
+		namespace := obj.(*apiv1.Namespace)
+		if namespace.Name == source.Namespace || namespace.Status.Phase == apiv1.NamespaceTerminating {
+			continue
+		}
+		if matches(patterns, namespace.Name) || (selector != nil && !selector.Empty() && selector.Matches(labels.Set(namespace.Labels))) {
+			targets = append(targets, namespace.Name)
+		}
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+func (r *Replicator) copy(ctx context.Context, source *apiv1.ConfigMap, namespace string) error {
+	sourceKey := source.Namespace + "/" + source.Name
+	configMaps := r.clientset.CoreV1().ConfigMaps(namespace)
+
+	existing, err := configMaps.Get(source.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		replica := &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        source.Name,
+				Namespace:   namespace,
+				Labels:      map[string]string{SourceLabel: Hash(sourceKey)},
+				Annotations: map[string]string{SourceAnnotation: sourceKey},
+			},
+			Data:       source.Data,
+			BinaryData: source.BinaryData,
+		}
+		if _, err := configMaps.Create(replica); err != nil {
+			return fmt.Errorf("failed to create %s/%s: %v", namespace, source.Name, err)
+		}
+		log.Debug("created replica %s/%s of %s", namespace, source.Name, sourceKey)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s/%s: %v", namespace, source.Name, err)
+	}
+
+	if existing.Annotations[SourceAnnotation] != sourceKey {
+		return fmt.Errorf("configmap %s/%s already exists and is not a replica of %s", namespace, source.Name, sourceKey)
+	}
+	if sameData(existing, source) {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	replica := existing.DeepCopy()
+	replica.Data = source.Data
+	replica.BinaryData = source.BinaryData
+	if _, err := configMaps.Update(replica); err != nil {
+		return fmt.Errorf("failed to update %s/%s: %v", namespace, source.Name, err)
+	}
+	log.Debug("updated replica %s/%s of %s", namespace, source.Name, sourceKey)
+	return nil
+}
+
+// prune deletes the copies of namespace/name outside of targets.
+func (r *Replicator) prune(ctx context.Context, namespace, name string, targets []string) error {
+	sourceKey := namespace + "/" + name
+	replicas, err := r.clientset.CoreV1().ConfigMaps(apiv1.NamespaceAll).List(metav1.ListOptions{
+		LabelSelector: labels.Set{SourceLabel: Hash(sourceKey)}.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list replicas of %s: %v", sourceKey, err)
+	}
+
+	keep := map[string]bool{}
+	for _, target := range targets {
+		keep[target] = true
+	}
+	var failed []string
+	for _, replica := range replicas.Items {
+		// The label is only a hash, the annotation tells which source it is.
+		if keep[replica.Namespace] || replica.Annotations[SourceAnnotation] != sourceKey {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := r.clientset.CoreV1().ConfigMaps(replica.Namespace).Delete(replica.Name, nil)
+		if err != nil && !apierrors.IsNotFound(err) {
+			failed = append(failed, fmt.Sprintf("failed to delete %s/%s: %v", replica.Namespace, replica.Name, err))
+			continue
+		}
+		log.Debug("deleted replica %s/%s of %s", replica.Namespace, replica.Name, sourceKey)
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Hash returns the value of SourceLabel for the source namespace/name, which
+// is too long for a label value in full.
+func Hash(sourceKey string) string {
+	sum := sha256.Sum256([]byte(sourceKey))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+func sameData(a, b *apiv1.ConfigMap) bool {
+	return (len(a.Data) == 0 && len(b.Data) == 0 || reflect.DeepEqual(a.Data, b.Data)) &&
+		(len(a.BinaryData) == 0 && len(b.BinaryData) == 0 || reflect.DeepEqual(a.BinaryData, b.BinaryData))
+}
+
+func matches(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Run watches namespaces and replicas until stopCh is closed. Replicate must
+// not be called before HasSynced, as copies in the namespaces not seen yet
+// would look stale and be deleted.
+func (r *Replicator) Run(stopCh <-chan struct{}) {
+	go r.replicaInformer.Run(stopCh)
+	r.namespaceInformer.Run(stopCh)
+}
+
+func (r *Replicator) HasSynced() bool {
+	return r.namespaceInformer.HasSynced() && r.replicaInformer.HasSynced()
+}
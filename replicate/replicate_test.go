@@ -0,0 +1,245 @@
+package replicate_test
+
+import (
+	"context"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/replicate"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+var _ = Describe("Replicator", func() {
+	var (
+		clientset  *fake.Clientset
+		replicator *replicate.Replicator
+		source     *apiv1.ConfigMap
+		objects    []runtime.Object
+		sources    cache.Indexer
+		stopCh     chan struct{}
+		mu         sync.Mutex
+		enqueued   []string
+	)
+
+	namespace := func(name string, labels map[string]string) *apiv1.Namespace {
+		return &apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	BeforeEach(func() {
+		source = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "platform",
+				Name:        "ca",
+				Annotations: map[string]string{replicate.ToAnnotation: "team-*"},
+			},
+			Data:       map[string]string{"ca.pem": "a certificate"},
+			BinaryData: map[string][]byte{"ca.der": []byte("a der")},
+		}
+		objects = []runtime.Object{
+			namespace("platform", nil),
+			namespace("team-a", nil),
+			namespace("team-b", map[string]string{"ca": "true"}),
+			namespace("other", map[string]string{"ca": "true"}),
+		}
+		mu.Lock()
+		enqueued = nil
+		mu.Unlock()
+	})
+
+	JustBeforeEach(func() {
+		clientset = fake.NewSimpleClientset(append(objects, source)...)
+		sources = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{replicate.IndexName: replicate.IndexFunc})
+		Expect(sources.Add(source)).To(Succeed())
+		replicator = replicate.New(clientset, []string{"platform"}, sources, func(key string) {
+			mu.Lock()
+			defer mu.Unlock()
+			enqueued = append(enqueued, key)
+		})
+		stopCh = make(chan struct{})
+		go replicator.Run(stopCh)
+		Eventually(replicator.HasSynced).Should(BeTrue())
+	})
+
+	AfterEach(func() {
+		close(stopCh)
+	})
+
+	replicas := func() map[string]apiv1.ConfigMap {
+		list, err := clientset.CoreV1().ConfigMaps(apiv1.NamespaceAll).List(metav1.ListOptions{
+			LabelSelector: replicate.SourceLabel + "=" + replicate.Hash("platform/ca"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		byNamespace := map[string]apiv1.ConfigMap{}
+		for _, replica := range list.Items {
+			byNamespace[replica.Namespace] = replica
+		}
+		return byNamespace
+	}
+
+	It("copies the data into the namespaces the annotation lists", func() {
+		Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+
+		copies := replicas()
+		Expect(copies).To(HaveLen(2))
+		Expect(copies).To(HaveKey("team-a"))
+		Expect(copies).To(HaveKey("team-b"))
+		replica := copies["team-a"]
+		Expect(replica.Name).To(Equal("ca"))
+		Expect(replica.Data).To(Equal(source.Data))
+		Expect(replica.BinaryData).To(Equal(source.BinaryData))
+		Expect(replica.Annotations).To(Equal(map[string]string{replicate.SourceAnnotation: "platform/ca"}))
+	})
+
+	When("namespaces are selected by label", func() {
+		BeforeEach(func() {
+			source.Annotations = map[string]string{replicate.ToSelectorAnnotation: "ca=true"}
+		})
+
+		It("copies the data into the matching namespaces", func() {
+			Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+			Expect(replicas()).To(SatisfyAll(HaveLen(2), HaveKey("team-b"), HaveKey("other")))
+		})
+	})
+
+	It("updates copies whose data is out of date", func() {
+		Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+
+		source.Data = map[string]string{"ca.pem": "a new certificate"}
+		Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+		for _, replica := range replicas() {
+			Expect(replica.Data).To(Equal(source.Data))
+		}
+	})
+
+	It("deletes copies in namespaces that are no longer selected", func() {
+		Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+
+		source.Annotations[replicate.ToAnnotation] = "team-a"
+		Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+		Expect(replicas()).To(SatisfyAll(HaveLen(1), HaveKey("team-a")))
+	})
+
+	It("removes every copy once the source is deleted", func() {
+		Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+		// Remove skips sources without copies until the copies are watched.
+		Eventually(func() map[string]apiv1.ConfigMap {
+			Expect(replicator.Remove(context.Background(), "platform", "ca")).To(Succeed())
+			return replicas()
+		}).Should(BeEmpty())
+	})
+
+	It("doesn't list replicas of sources without copies", func() {
+		clientset.ClearActions()
+		Expect(replicator.Remove(context.Background(), "platform", "ca")).To(Succeed())
+		Expect(clientset.Actions()).To(BeEmpty())
+	})
+
+	It("requeues the source when a copy is changed or deleted", func() {
+		Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+		Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return enqueued
+		}).Should(ContainElement("platform/ca"))
+		mu.Lock()
+		enqueued = nil
+		mu.Unlock()
+
+		Expect(clientset.CoreV1().ConfigMaps("team-a").Delete("ca", nil)).To(Succeed())
+		Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return enqueued
+		}).Should(ContainElement("platform/ca"))
+	})
+
+	When("a configmap of the same name that isn't a copy exists", func() {
+		BeforeEach(func() {
+			objects = append(objects, &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "ca"},
+				Data:       map[string]string{"ca.pem": "their own"},
+			})
+		})
+
+		It("leaves it alone, copies into the other namespaces and fails", func() {
+			err := replicator.Replicate(context.Background(), source)
+			Expect(err).To(MatchError(ContainSubstring("configmap team-a/ca already exists and is not a replica of platform/ca")))
+
+			existing, err := clientset.CoreV1().ConfigMaps("team-a").Get("ca", metav1.GetOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(existing.Data).To(Equal(map[string]string{"ca.pem": "their own"}))
+			Expect(replicas()).To(SatisfyAll(HaveLen(1), HaveKey("team-b")))
+		})
+	})
+
+	When("the source is in a namespace that isn't replicated from", func() {
+		BeforeEach(func() {
+			source.Namespace = "team-a"
+		})
+
+		It("fails without copying", func() {
+			err := replicator.Replicate(context.Background(), source)
+			Expect(err).To(MatchError("replicating configmaps from namespace team-a is not allowed"))
+			_, err = clientset.CoreV1().ConfigMaps("team-b").Get("ca", metav1.GetOptions{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	It("doesn't copy into namespaces being deleted", func() {
+		terminating := namespace("team-c", nil)
+		terminating.Status.Phase = apiv1.NamespaceTerminating
+		_, err := clientset.CoreV1().Namespaces().Create(terminating)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return enqueued
+		}).Should(ContainElement("platform/ca"))
+
+		Expect(replicator.Replicate(context.Background(), source)).To(Succeed())
+		Expect(replicas()).NotTo(HaveKey("team-c"))
+	})
+
+	It("requeues the sources when a namespace changes", func() {
+		mu.Lock()
+		enqueued = nil
+		mu.Unlock()
+
+		_, err := clientset.CoreV1().Namespaces().Create(namespace("team-d", nil))
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return enqueued
+		}).Should(Equal([]string{"platform/ca"}))
+	})
+
+	It("rejects invalid selectors", func() {
+		source.Annotations[replicate.ToSelectorAnnotation] = "ca in"
+		Expect(replicator.Replicate(context.Background(), source)).To(MatchError(ContainSubstring("invalid x-k8s.io/replicate-to-selector annotation")))
+	})
+})
+
+var _ = Describe("IndexFunc", func() {
+	It("indexes the configmaps to replicate by namespace", func() {
+		values, err := replicate.IndexFunc(&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "platform",
+			Name:        "ca",
+			Annotations: map[string]string{replicate.ToSelectorAnnotation: "ca=true"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(Equal([]string{"platform"}))
+
+		values, err = replicate.IndexFunc(&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "platform", Name: "other"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(values).To(BeEmpty())
+	})
+})
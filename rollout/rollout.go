@@ -0,0 +1,240 @@
+package rollout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aclevername/config-map-controller/log"
+
+	"github.com/google/uuid"
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// OptInKey set to "true" as an annotation on a ConfigMap restarts every
+	// workload consuming it, and as a label on a workload restarts it when
+	// any ConfigMap it consumes is updated.
+	OptInKey = "x-k8s.io/restart-on-change"
+
+	// ChecksumAnnotationPrefix prefixes the pod template annotation holding
+	// the checksum of the data of a consumed ConfigMap.
+	ChecksumAnnotationPrefix = "checksum.x-k8s.io/"
+
+	ReasonRestarted     = "Restarted"
+	ReasonRestartFailed = "RestartFailed"
+)
+
+// Restarter triggers rolling restarts of the Deployments, StatefulSets and
+// DaemonSets consuming a ConfigMap by patching a checksum of its data into
+// their pod templates.
+type Restarter struct {
+	clientset kubernetes.Interface
+}
+
+func New(clientset kubernetes.Interface) *Restarter {
+	return &Restarter{clientset: clientset}
+}
+
+// workload is the part of a Deployment, StatefulSet or DaemonSet needed to
+// restart it.
+type workload struct {
+	kind     string
+	meta     metav1.ObjectMeta
+	template apiv1.PodTemplateSpec
+	patch    func(data []byte) error
+}
+
+// Restart patches the checksum of the data of configMap into the pod template
+// of every opted in workload in its namespace that consumes it through a
+// volume, envFrom or valueFrom, recording an event on both. Workloads already
+// carrying the checksum are left alone.
+func (r *Restarter) Restart(ctx context.Context, configMap *apiv1.ConfigMap) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	workloads, err := r.workloads(configMap.Namespace)
+	if err != nil {
+		return err
+	}
+
+	key := ChecksumAnnotation(configMap.Name)
+	checksum := Checksum(configMap)
+	optedIn := configMap.Annotations[OptInKey] == "true"
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{key: checksum},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, w := range workloads {
+		if !optedIn && w.meta.Labels[OptInKey] != "true" {
+			continue
+		}
+		if !References(w.template.Spec, configMap.Name) || w.template.Annotations[key] == checksum {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		logger := log.With(log.Fields{"namespace": configMap.Namespace, "name": configMap.Name, "workload": w.kind + "/" + w.meta.Name})
+		if err := w.patch(patch); err != nil {
+			logger.With(log.Fields{"error": err}).Error("failed to restart workload")
+			r.event(configMapReference(configMap), apiv1.EventTypeWarning, ReasonRestartFailed, fmt.Sprintf("failed to restart %s %s: %v", w.kind, w.meta.Name, err))
+			failed = append(failed, fmt.Sprintf("%s %s: %v", w.kind, w.meta.Name, err))
+			continue
+		}
+
+		logger.Debug("restarted workload")
+		r.event(configMapReference(configMap), apiv1.EventTypeNormal, ReasonRestarted, fmt.Sprintf("restarted %s %s to pick up the updated data", w.kind, w.meta.Name))
+		r.event(apiv1.ObjectReference{
+			Kind:       w.kind,
+			APIVersion: appsv1.SchemeGroupVersion.String(),
+			Namespace:  w.meta.Namespace,
+			Name:       w.meta.Name,
+			UID:        w.meta.UID,
+		}, apiv1.EventTypeNormal, ReasonRestarted, fmt.Sprintf("restarting to pick up the updated data of ConfigMap %s", configMap.Name))
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to restart %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+func (r *Restarter) workloads(namespace string) ([]workload, error) {
+	apps := r.clientset.AppsV1()
+	var workloads []workload
+
+	deployments, err := apps.Deployments(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %v", err)
+	}
+	for _, d := range deployments.Items {
+		name := d.Name
+		workloads = append(workloads, workload{kind: "Deployment", meta: d.ObjectMeta, template: d.Spec.Template, patch: func(data []byte) error {
+			_, err := apps.Deployments(namespace).Patch(name, types.MergePatchType, data)
+			return err
+		}})
+	}
+
+	statefulSets, err := apps.StatefulSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %v", err)
+	}
+	for _, s := range statefulSets.Items {
+		name := s.Name
+		workloads = append(workloads, workload{kind: "StatefulSet", meta: s.ObjectMeta, template: s.Spec.Template, patch: func(data []byte) error {
+			_, err := apps.StatefulSets(namespace).Patch(name, types.MergePatchType, data)
+			return err
+		}})
+	}
+
+	daemonSets, err := apps.DaemonSets(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %v", err)
+	}
+	for _, d := range daemonSets.Items {
+		name := d.Name
+		workloads = append(workloads, workload{kind: "DaemonSet", meta: d.ObjectMeta, template: d.Spec.Template, patch: func(data []byte) error {
+			_, err := apps.DaemonSets(namespace).Patch(name, types.MergePatchType, data)
+			return err
+		}})
+	}
+
+	return workloads, nil
+}
+
+// References reports whether spec consumes the ConfigMap name through a
+// volume, a projected volume, envFrom or valueFrom.
+func References(spec apiv1.PodSpec, name string) bool {
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil && volume.ConfigMap.Name == name {
+			return true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil && source.ConfigMap.Name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]apiv1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil && envFrom.ConfigMapRef.Name == name {
+				return true
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil && env.ValueFrom.ConfigMapKeyRef.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ChecksumAnnotation returns the pod template annotation holding the checksum
+// of the ConfigMap name. Names too long for an annotation are hashed.
+func ChecksumAnnotation(name string) string {
+	if len(name) > 63 {
+		sum := sha256.Sum256([]byte(name))
+		name = name[:46] + "-" + hex.EncodeToString(sum[:])[:16]
+	}
+	return ChecksumAnnotationPrefix + name
+}
+
+// Checksum returns the sha256 of the data and binary data of configMap.
+func Checksum(configMap *apiv1.ConfigMap) string {
+	// map keys are marshalled in order, so equal data has equal checksums.
+	data, _ := json.Marshal([]interface{}{configMap.Data, configMap.BinaryData})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func configMapReference(configMap *apiv1.ConfigMap) apiv1.ObjectReference {
+	return apiv1.ObjectReference{
+		Kind:      "ConfigMap",
+		Namespace: configMap.Namespace,
+		Name:      configMap.Name,
+		UID:       configMap.UID,
+	}
+}
+
+func (r *Restarter) event(object apiv1.ObjectReference, eventType, reason, message string) {
+	event := apiv1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "config-map-controller" + uuid.New().String(),
+			Namespace: object.Namespace,
+		},
+		InvolvedObject: object,
+		Source:         apiv1.EventSource{Component: "config-map-controller"},
+		Type:           eventType,
+		Reason:         reason,
+		Message:        message,
+		FirstTimestamp: metav1.Now(),
+	}
+	if _, err := r.clientset.CoreV1().Events(object.Namespace).Create(&event); err != nil {
+		log.With(log.Fields{"namespace": object.Namespace, "name": object.Name, "error": err}).Error("error creating event")
+	}
+}
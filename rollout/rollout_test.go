@@ -0,0 +1,167 @@
+package rollout_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/rollout"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Restarter", func() {
+	var (
+		clientset *fake.Clientset
+		configMap *apiv1.ConfigMap
+		objects   []runtime.Object
+	)
+
+	envFrom := apiv1.PodSpec{Containers: []apiv1.Container{{
+		Name:    "app",
+		EnvFrom: []apiv1.EnvFromSource{{ConfigMapRef: &apiv1.ConfigMapEnvSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "jokes"}}}},
+	}}}
+	volume := apiv1.PodSpec{Volumes: []apiv1.Volume{{
+		Name:         "jokes",
+		VolumeSource: apiv1.VolumeSource{ConfigMap: &apiv1.ConfigMapVolumeSource{LocalObjectReference: apiv1.LocalObjectReference{Name: "jokes"}}},
+	}}}
+	other := apiv1.PodSpec{Containers: []apiv1.Container{{Name: "app"}}}
+
+	objectMeta := func(name string, labels map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Namespace: "team-a", Name: name, Labels: labels}
+	}
+
+	BeforeEach(func() {
+		configMap = &apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "jokes", Annotations: map[string]string{rollout.OptInKey: "true"}},
+			Data:       map[string]string{"joke": "a joke"},
+		}
+		objects = []runtime.Object{
+			&appsv1.Deployment{ObjectMeta: objectMeta("web", nil), Spec: appsv1.DeploymentSpec{Template: apiv1.PodTemplateSpec{Spec: envFrom}}},
+			&appsv1.StatefulSet{ObjectMeta: objectMeta("db", nil), Spec: appsv1.StatefulSetSpec{Template: apiv1.PodTemplateSpec{Spec: volume}}},
+			&appsv1.DaemonSet{ObjectMeta: objectMeta("agent", nil), Spec: appsv1.DaemonSetSpec{Template: apiv1.PodTemplateSpec{Spec: other}}},
+		}
+	})
+
+	restart := func() error {
+		clientset = fake.NewSimpleClientset(objects...)
+		return rollout.New(clientset).Restart(context.Background(), configMap)
+	}
+
+	checksumOf := func(template apiv1.PodTemplateSpec) string {
+		return template.Annotations[rollout.ChecksumAnnotation("jokes")]
+	}
+
+	getDeployment := func() *appsv1.Deployment {
+		deployment, err := clientset.AppsV1().Deployments("team-a").Get("web", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return deployment
+	}
+
+	eventMessages := func() []string {
+		events, err := clientset.CoreV1().Events("team-a").List(metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		var messages []string
+		for _, event := range events.Items {
+			messages = append(messages, event.InvolvedObject.Kind+": "+event.Message)
+		}
+		return messages
+	}
+
+	It("patches the checksum into the workloads consuming the configmap", func() {
+		Expect(restart()).To(Succeed())
+
+		Expect(checksumOf(getDeployment().Spec.Template)).To(Equal(rollout.Checksum(configMap)))
+		statefulSet, err := clientset.AppsV1().StatefulSets("team-a").Get("db", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksumOf(statefulSet.Spec.Template)).To(Equal(rollout.Checksum(configMap)))
+		daemonSet, err := clientset.AppsV1().DaemonSets("team-a").Get("agent", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checksumOf(daemonSet.Spec.Template)).To(BeEmpty())
+
+		Expect(eventMessages()).To(ConsistOf(
+			"ConfigMap: restarted Deployment web to pick up the updated data",
+			"Deployment: restarting to pick up the updated data of ConfigMap jokes",
+			"ConfigMap: restarted StatefulSet db to pick up the updated data",
+			"StatefulSet: restarting to pick up the updated data of ConfigMap jokes",
+		))
+	})
+
+	It("leaves workloads already carrying the checksum alone", func() {
+		objects[0].(*appsv1.Deployment).Spec.Template.Annotations = map[string]string{rollout.ChecksumAnnotation("jokes"): rollout.Checksum(configMap)}
+		Expect(restart()).To(Succeed())
+		Expect(eventMessages()).NotTo(ContainElement(ContainSubstring("Deployment web")))
+	})
+
+	When("the configmap doesn't opt in", func() {
+		BeforeEach(func() {
+			configMap.Annotations = nil
+			objects[1].(*appsv1.StatefulSet).Labels = map[string]string{rollout.OptInKey: "true"}
+		})
+
+		It("only restarts workloads opting in by label", func() {
+			Expect(restart()).To(Succeed())
+			Expect(checksumOf(getDeployment().Spec.Template)).To(BeEmpty())
+			Expect(eventMessages()).To(ConsistOf(
+				"ConfigMap: restarted StatefulSet db to pick up the updated data",
+				"StatefulSet: restarting to pick up the updated data of ConfigMap jokes",
+			))
+		})
+	})
+
+	When("patching a workload fails", func() {
+		It("restarts the others and reports the failure", func() {
+			clientset = fake.NewSimpleClientset(objects...)
+			clientset.PrependReactor("patch", "deployments", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.New("forbidden")
+			})
+
+			err := rollout.New(clientset).Restart(context.Background(), configMap)
+			Expect(err).To(MatchError("failed to restart Deployment web: forbidden"))
+			Expect(eventMessages()).To(ContainElement("ConfigMap: failed to restart Deployment web: forbidden"))
+			Expect(eventMessages()).To(ContainElement("ConfigMap: restarted StatefulSet db to pick up the updated data"))
+		})
+	})
+})
+
+var _ = Describe("References", func() {
+	It("finds configmaps used through volumes, envFrom and valueFrom", func() {
+		projected := apiv1.PodSpec{Volumes: []apiv1.Volume{{Name: "all", VolumeSource: apiv1.VolumeSource{Projected: &apiv1.ProjectedVolumeSource{
+			Sources: []apiv1.VolumeProjection{{ConfigMap: &apiv1.ConfigMapProjection{LocalObjectReference: apiv1.LocalObjectReference{Name: "jokes"}}}},
+		}}}}}
+		Expect(rollout.References(projected, "jokes")).To(BeTrue())
+
+		valueFrom := apiv1.PodSpec{InitContainers: []apiv1.Container{{Env: []apiv1.EnvVar{{Name: "JOKE", ValueFrom: &apiv1.EnvVarSource{
+			ConfigMapKeyRef: &apiv1.ConfigMapKeySelector{LocalObjectReference: apiv1.LocalObjectReference{Name: "jokes"}, Key: "joke"},
+		}}}}}}
+		Expect(rollout.References(valueFrom, "jokes")).To(BeTrue())
+		Expect(rollout.References(valueFrom, "other")).To(BeFalse())
+	})
+})
+
+var _ = Describe("ChecksumAnnotation", func() {
+	It("keeps long configmap names within the annotation name limit", func() {
+		key := rollout.ChecksumAnnotation(strings.Repeat("a", 253))
+		Expect(len(strings.TrimPrefix(key, rollout.ChecksumAnnotationPrefix))).To(BeNumerically("<=", 63))
+		Expect(key).NotTo(Equal(rollout.ChecksumAnnotation(strings.Repeat("a", 252))))
+	})
+})
+
+var _ = Describe("Checksum", func() {
+	It("changes with the data", func() {
+		configMap := &apiv1.ConfigMap{Data: map[string]string{"a": "1", "b": "2"}}
+		before := rollout.Checksum(configMap)
+		Expect(rollout.Checksum(configMap.DeepCopy())).To(Equal(before))
+
+		configMap.Data["b"] = "3"
+		Expect(rollout.Checksum(configMap)).NotTo(Equal(before))
+	})
+})
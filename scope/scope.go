@@ -0,0 +1,408 @@
+package scope
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aclevername/config-map-controller/log"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Scope restricts which ConfigMaps the controller watches and processes. The
+// zero value watches every ConfigMap in the cluster.
+type Scope struct {
+	Namespaces        []string `json:"namespaces"`
+	ExcludeNamespaces []string `json:"excludeNamespaces"`
+	LabelSelector     string   `json:"labelSelector"`
+	NamespaceSelector string   `json:"namespaceLabelSelector"`
+}
+
+func (s Scope) Validate() error {
+	if _, err := labels.Parse(s.LabelSelector); err != nil {
+		return fmt.Errorf("invalid label selector '%s': %v", s.LabelSelector, err)
+	}
+
+	if _, err := labels.Parse(s.NamespaceSelector); err != nil {
+		return fmt.Errorf("invalid namespace label selector '%s': %v", s.NamespaceSelector, err)
+	}
+
+	for _, excluded := range s.ExcludeNamespaces {
+		for _, namespace := range s.Namespaces {
+			if namespace == excluded {
+				return fmt.Errorf("namespace %s is both watched and excluded", namespace)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether obj is in the namespaces of the scope and matches
+// its label selector. The namespace label selector isn't checked, as it needs
+// the labels of the namespace.
+func (s Scope) Matches(obj metav1.Object) bool {
+	if s.excluded(obj.GetNamespace()) {
+		return false
+	}
+	if len(s.Namespaces) > 0 && !contains(s.Namespaces, obj.GetNamespace()) {
+		return false
+	}
+	selector, err := labels.Parse(s.LabelSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Scope) watchNamespaces() []string {
+	if len(s.Namespaces) == 0 {
+		return []string{apiv1.NamespaceAll}
+	}
+	return s.Namespaces
+}
+
+// fieldSelector excludes ExcludeNamespaces on the API server when every
+// namespace is watched, so their ConfigMaps are never sent or cached.
+func (s Scope) fieldSelector(namespace string) string {
+	if namespace != apiv1.NamespaceAll {
+		return ""
+	}
+	var selectors []fields.Selector
+	for _, excluded := range s.ExcludeNamespaces {
+		selectors = append(selectors, fields.OneTermNotEqualSelector("metadata.namespace", excluded))
+	}
+	return fields.AndSelectors(selectors...).String()
+}
+
+func (s Scope) excluded(namespace string) bool {
+	return contains(s.ExcludeNamespaces, namespace)
+}
+
+// ParseList splits a comma separated flag value, ignoring empty entries.
+func ParseList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Informer runs one ConfigMap informer per watched namespace and only passes
+// events for ConfigMaps inside the scope on to the handler.
+type Informer struct {
+	clientset  kubernetes.Interface
+	scope      Scope
+	filtered   cache.ResourceEventHandler
+	informers  []cache.Controller
+	indexers   []cache.Indexer
+	namespaces cache.Store
+}
+
+// NewInformer watches the ConfigMaps in scope. Every ConfigMap is passed to
+// handler again as an update each resync period, disabled when zero.
+func NewInformer(clientset kubernetes.Interface, scope Scope, resync time.Duration, handler cache.ResourceEventHandler) (*Informer, error) {
+	listWatch := func(namespace string) *cache.ListWatch {
+		return configMapListWatch(clientset, namespace, scope.LabelSelector, scope.fieldSelector(namespace))
+	}
+	return newInformer(clientset, scope, resync, listWatch, &apiv1.ConfigMap{}, handler, nil)
+}
+
+// NewMetadataInformer watches ConfigMaps through the metadata client so only
+// their metadata is cached. Events are only passed on for ConfigMaps carrying
+// annotationKey, with only their metadata, use Get for the full object.
+func NewMetadataInformer(clientset kubernetes.Interface, metadataClient metadata.Interface, scope Scope, resync time.Duration, annotationKey string, handler cache.ResourceEventHandler) (*Informer, error) {
+	listWatch := func(namespace string) *cache.ListWatch {
+		return metadataListWatch(metadataClient, namespace, scope.LabelSelector, scope.fieldSelector(namespace))
+	}
+	annotated := func(obj interface{}) bool {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return false
+		}
+		_, ok := accessor.GetAnnotations()[annotationKey]
+		return ok
+	}
+	return newInformer(clientset, scope, resync, listWatch, &metav1.PartialObjectMetadata{}, handler, annotated)
+}
+
+func newInformer(clientset kubernetes.Interface, scope Scope, resync time.Duration, listWatch func(namespace string) *cache.ListWatch, objType runtime.Object, handler cache.ResourceEventHandler, filter func(obj interface{}) bool) (*Informer, error) {
+	if err := scope.Validate(); err != nil {
+		return nil, err
+	}
+
+	i := &Informer{
+		clientset: clientset,
+		scope:     scope,
+	}
+
+	i.filtered = cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			return i.includes(obj) && (filter == nil || filter(obj))
+		},
+		Handler: handler,
+	}
+
+	for _, namespace := range scope.watchNamespaces() {
+		indexer, informer := cache.NewIndexerInformer(
+			listWatch(namespace),
+			objType,
+			resync,
+			i.filtered,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		)
+		i.indexers = append(i.indexers, indexer)
+		i.informers = append(i.informers, informer)
+	}
+
+	if scope.NamespaceSelector != "" {
+		store, informer := cache.NewInformer(
+			namespaceListWatch(clientset, scope.NamespaceSelector),
+			&apiv1.Namespace{},
+			0,
+			cache.ResourceEventHandlerFuncs{
+				AddFunc: i.namespaceAdded,
+			},
+		)
+		i.namespaces = store
+		i.informers = append(i.informers, informer)
+	}
+
+	return i, nil
+}
+
+func (i *Informer) Run(stopCh <-chan struct{}) {
+	for _, informer := range i.informers {
+		go informer.Run(stopCh)
+	}
+	<-stopCh
+}
+
+func (i *Informer) HasSynced() bool {
+	for _, informer := range i.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (i *Informer) LastSyncResourceVersion() string {
+	var versions []string
+	for _, informer := range i.informers {
+		versions = append(versions, informer.LastSyncResourceVersion())
+	}
+	return strings.Join(versions, ",")
+}
+
+// Get returns the ConfigMap with namespace and name if it is cached and in
+// scope, or a NotFound error. ConfigMaps cached by a metadata informer are
+// fetched in full from the API server.
+func (i *Informer) Get(namespace, name string) (*apiv1.ConfigMap, error) {
+	key := namespace + "/" + name
+	for _, indexer := range i.indexers {
+		obj, exists, err := indexer.GetByKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if !exists || !i.includes(obj) {
+			continue
+		}
+		if configMap, ok := obj.(*apiv1.ConfigMap); ok {
+			return configMap, nil
+		}
+		return i.clientset.CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	}
+	return nil, apierrors.NewNotFound(apiv1.Resource("configmaps"), name)
+}
+
+// AddIndexers adds indexers to the cache of every watched namespace. It must
+// be called before the informer is run.
+func (i *Informer) AddIndexers(indexers cache.Indexers) error {
+	for _, indexer := range i.indexers {
+		if err := indexer.AddIndexers(indexers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ByIndex returns the cached ConfigMaps in scope whose indexName index
+// matches value. Metadata informers return metadata objects.
+func (i *Informer) ByIndex(indexName, value string) ([]interface{}, error) {
+	var matching []interface{}
+	for _, indexer := range i.indexers {
+		objs, err := indexer.ByIndex(indexName, value)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			if i.includes(obj) {
+				matching = append(matching, obj)
+			}
+		}
+	}
+	return matching, nil
+}
+
+// Includes reports whether configMap is inside the scope, for ConfigMaps that
+// didn't come from the informer such as ones being created. ConfigMaps in
+// namespaces matching the namespace label selector are only included once the
+// informer has seen the namespace.
+func (i *Informer) Includes(configMap metav1.Object) bool {
+	return i.scope.Matches(configMap) && i.includes(configMap)
+}
+
+func (i *Informer) includes(obj interface{}) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+
+	namespace := accessor.GetNamespace()
+	if i.scope.excluded(namespace) {
+		return false
+	}
+
+	if i.namespaces != nil {
+		_, exists, err := i.namespaces.GetByKey(namespace)
+		if err != nil || !exists {
+			log.Debug("namespace %s does not match %s, ignoring %s/%s", namespace, i.scope.NamespaceSelector, namespace, accessor.GetName())
+			return false
+		}
+	}
+
+	return true
+}
+
+// namespaceAdded is called when a namespace starts matching the namespace
+// selector, so ConfigMaps that were filtered out until now get processed.
+func (i *Informer) namespaceAdded(obj interface{}) {
+	namespace, ok := obj.(*apiv1.Namespace)
+	if !ok || i.scope.excluded(namespace.Name) {
+		return
+	}
+
+	for _, indexer := range i.indexers {
+		configMaps, err := indexer.ByIndex(cache.NamespaceIndex, namespace.Name)
+		if err != nil {
+			log.Error("failed to list configmaps in namespace %s: %v", namespace.Name, err)
+			continue
+		}
+		for _, configMap := range configMaps {
+			i.filtered.OnAdd(configMap)
+		}
+	}
+}
+
+func configMapListWatch(clientset kubernetes.Interface, namespace, labelSelector, fieldSelector string) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = labelSelector
+			options.FieldSelector = fieldSelector
+			return clientset.CoreV1().ConfigMaps(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = labelSelector
+			options.FieldSelector = fieldSelector
+			return clientset.CoreV1().ConfigMaps(namespace).Watch(options)
+		},
+	}
+}
+
+func metadataListWatch(metadataClient metadata.Interface, namespace, labelSelector, fieldSelector string) *cache.ListWatch {
+	configMaps := metadataClient.Resource(apiv1.SchemeGroupVersion.WithResource("configmaps")).Namespace(namespace)
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = labelSelector
+			options.FieldSelector = fieldSelector
+			return configMaps.List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = labelSelector
+			options.FieldSelector = fieldSelector
+			return configMaps.Watch(options)
+		},
+	}
+}
+
+func namespaceListWatch(clientset kubernetes.Interface, labelSelector string) *cache.ListWatch {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = labelSelector
+			return clientset.CoreV1().Namespaces().List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = labelSelector
+			return clientset.CoreV1().Namespaces().Watch(options)
+		},
+	}
+}
+
+// List returns the ConfigMaps inside scope without starting an informer, for
+// runs that reconcile everything once.
+func List(clientset kubernetes.Interface, scope Scope) ([]apiv1.ConfigMap, error) {
+	if err := scope.Validate(); err != nil {
+		return nil, err
+	}
+
+	var matchingNamespaces map[string]bool
+	if scope.NamespaceSelector != "" {
+		namespaces, err := clientset.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: scope.NamespaceSelector})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %v", err)
+		}
+		matchingNamespaces = map[string]bool{}
+		for _, namespace := range namespaces.Items {
+			matchingNamespaces[namespace.Name] = true
+		}
+	}
+
+	var configMaps []apiv1.ConfigMap
+	for _, namespace := range scope.watchNamespaces() {
+		options := metav1.ListOptions{LabelSelector: scope.LabelSelector, FieldSelector: scope.fieldSelector(namespace), Limit: 500}
+		for {
+			list, err := clientset.CoreV1().ConfigMaps(namespace).List(options)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list configmaps: %v", err)
+			}
+			for _, configMap := range list.Items {
+				if scope.excluded(configMap.Namespace) {
+					continue
+				}
+				if matchingNamespaces != nil && !matchingNamespaces[configMap.Namespace] {
+					continue
+				}
+				configMaps = append(configMaps, configMap)
+			}
+			if list.Continue == "" {
+				break
+			}
+			options.Continue = list.Continue
+		}
+	}
+	return configMaps, nil
+}
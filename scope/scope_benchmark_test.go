@@ -0,0 +1,133 @@
+package scope_test
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aclevername/config-map-controller/scope"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	benchmarkConfigMaps = 2000
+	benchmarkDataSize   = 8 * 1024
+	benchmarkAnnotated  = 20
+)
+
+// BenchmarkInformerMemory compares the heap retained by the informer cache
+// when caching full ConfigMaps against caching metadata only. Run with
+// go test ./scope/ -run xxx -bench InformerMemory
+func BenchmarkInformerMemory(b *testing.B) {
+	var metadataObjects []k8sruntime.Object
+	for _, configMap := range benchmarkConfigMapList().Items {
+		metadataObjects = append(metadataObjects, toMetadata(&configMap))
+	}
+
+	b.Run("IndexerInformer", func(b *testing.B) {
+		clientset := benchmarkClientset()
+		measureInformer(b, func() cache.Controller {
+			listWatch := &cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (k8sruntime.Object, error) {
+					return clientset.CoreV1().ConfigMaps(apiv1.NamespaceAll).List(options)
+				},
+				WatchFunc: clientset.CoreV1().ConfigMaps(apiv1.NamespaceAll).Watch,
+			}
+			_, informer := cache.NewIndexerInformer(listWatch, &apiv1.ConfigMap{}, 0, cache.ResourceEventHandlerFuncs{}, cache.Indexers{})
+			return informer
+		})
+	})
+
+	b.Run("MetadataInformer", func(b *testing.B) {
+		clientset := benchmarkClientset()
+		metadataClient := metadatafake.NewSimpleMetadataClient(metadataScheme(), metadataObjects...)
+		measureInformer(b, func() cache.Controller {
+			informer, err := scope.NewMetadataInformer(clientset, metadataClient, scope.Scope{}, 0, "my-annotation", cache.ResourceEventHandlerFuncs{})
+			if err != nil {
+				b.Fatal(err)
+			}
+			return informer
+		})
+	})
+}
+
+// benchmarkClientset serves a freshly allocated list on every call, like a
+// real API server response would be, so the informer cache does not share
+// memory with the fake's tracker.
+func benchmarkClientset() *fake.Clientset {
+	clientset := fake.NewSimpleClientset()
+	for _, configMap := range benchmarkConfigMapList().Items {
+		if err := clientset.Tracker().Add(configMap.DeepCopy()); err != nil {
+			panic(err)
+		}
+	}
+	clientset.PrependReactor("list", "configmaps", func(action clienttesting.Action) (bool, k8sruntime.Object, error) {
+		return true, benchmarkConfigMapList(), nil
+	})
+	return clientset
+}
+
+func benchmarkConfigMapList() *apiv1.ConfigMapList {
+	list := &apiv1.ConfigMapList{}
+	for i := 0; i < benchmarkConfigMaps; i++ {
+		var annotations map[string]string
+		if i < benchmarkAnnotated {
+			annotations = map[string]string{"my-annotation": "key=example.com"}
+		}
+		list.Items = append(list.Items, apiv1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        fmt.Sprintf("configmap-%d", i),
+				Namespace:   "default",
+				Annotations: annotations,
+			},
+			Data: map[string]string{
+				"payload": strings.Repeat("x", benchmarkDataSize),
+			},
+		})
+	}
+	return list
+}
+
+func measureInformer(b *testing.B, newInformer func() cache.Controller) {
+	// Informers are kept running until the end so that memory released by
+	// one iteration is not subtracted from the next.
+	var (
+		total     uint64
+		informers []cache.Controller
+	)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	for n := 0; n < b.N; n++ {
+		before := heapInUse()
+
+		informer := newInformer()
+		go informer.Run(stopCh)
+		for !informer.HasSynced() {
+			runtime.Gosched()
+		}
+
+		after := heapInUse()
+		if after > before {
+			total += after - before
+		}
+		informers = append(informers, informer)
+	}
+	b.ReportMetric(float64(total)/float64(b.N), "cache-bytes/op")
+	runtime.KeepAlive(informers)
+}
+
+func heapInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
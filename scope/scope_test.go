@@ -0,0 +1,373 @@
+package scope_test
+
+import (
+	"sync"
+
+	"github.com/aclevername/config-map-controller/scope"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+var _ = Describe("Scope", func() {
+	Describe("Validate", func() {
+		It("accepts the zero value", func() {
+			Expect(scope.Scope{}.Validate()).To(Succeed())
+		})
+
+		It("rejects an invalid label selector", func() {
+			err := scope.Scope{LabelSelector: "a in (b"}.Validate()
+			Expect(err).To(MatchError(ContainSubstring("invalid label selector 'a in (b'")))
+		})
+
+		It("rejects an invalid namespace label selector", func() {
+			err := scope.Scope{NamespaceSelector: "!!"}.Validate()
+			Expect(err).To(MatchError(ContainSubstring("invalid namespace label selector '!!'")))
+		})
+
+		It("rejects a namespace that is both watched and excluded", func() {
+			err := scope.Scope{Namespaces: []string{"a", "b"}, ExcludeNamespaces: []string{"b"}}.Validate()
+			Expect(err).To(MatchError("namespace b is both watched and excluded"))
+		})
+	})
+
+	Describe("ParseList", func() {
+		It("splits on commas and drops empty entries", func() {
+			Expect(scope.ParseList(" a, b,,c ")).To(Equal([]string{"a", "b", "c"}))
+			Expect(scope.ParseList("")).To(BeEmpty())
+		})
+	})
+
+	Describe("Informer", func() {
+		var (
+			fakeClient *fake.Clientset
+			watchScope scope.Scope
+			informer   *scope.Informer
+			stopCh     chan struct{}
+			mu         sync.Mutex
+			received   []string
+			indexers   cache.Indexers
+		)
+
+		configMap := func(namespace, name string, labels map[string]string) *apiv1.ConfigMap {
+			return &apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					Labels:    labels,
+				},
+			}
+		}
+
+		receivedNames := func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string{}, received...)
+		}
+
+		BeforeEach(func() {
+			received = nil
+			watchScope = scope.Scope{}
+			indexers = nil
+			fakeClient = fake.NewSimpleClientset(
+				&apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"curl-me": "true"}}},
+				&apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+				configMap("team-a", "a", map[string]string{"opt-in": "true"}),
+				configMap("team-b", "b", nil),
+				configMap("kube-system", "c", nil),
+			)
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			informer, err = scope.NewInformer(fakeClient, watchScope, 0, cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					mu.Lock()
+					defer mu.Unlock()
+					cm := obj.(*apiv1.ConfigMap)
+					received = append(received, cm.Namespace+"/"+cm.Name)
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(informer.AddIndexers(indexers)).To(Succeed())
+
+			stopCh = make(chan struct{})
+			go informer.Run(stopCh)
+			Eventually(informer.HasSynced).Should(BeTrue())
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+		})
+
+		When("indexers are added", func() {
+			BeforeEach(func() {
+				indexers = cache.Indexers{"by-name": func(obj interface{}) ([]string, error) {
+					return []string{obj.(*apiv1.ConfigMap).Name[:1]}, nil
+				}}
+				fakeClient = fake.NewSimpleClientset(configMap("team-a", "a1", nil), configMap("team-a", "a2", nil), configMap("kube-system", "a3", nil))
+				watchScope = scope.Scope{ExcludeNamespaces: []string{"kube-system"}}
+			})
+
+			It("looks up configmaps in scope by index", func() {
+				matching, err := informer.ByIndex("by-name", "a")
+				Expect(err).NotTo(HaveOccurred())
+				var names []string
+				for _, obj := range matching {
+					names = append(names, obj.(*apiv1.ConfigMap).Name)
+				}
+				Expect(names).To(ConsistOf("a1", "a2"))
+			})
+		})
+
+		When("no scope is set", func() {
+			It("receives every configmap", func() {
+				Eventually(receivedNames).Should(ConsistOf("team-a/a", "team-b/b", "kube-system/c"))
+			})
+		})
+
+		When("namespaces are listed", func() {
+			BeforeEach(func() {
+				watchScope.Namespaces = []string{"team-a", "team-b"}
+			})
+
+			It("only receives configmaps in those namespaces", func() {
+				Eventually(receivedNames).Should(ConsistOf("team-a/a", "team-b/b"))
+				Consistently(receivedNames).ShouldNot(ContainElement("kube-system/c"))
+			})
+
+			It("only includes configmaps in those namespaces", func() {
+				Expect(informer.Includes(configMap("team-a", "new", nil))).To(BeTrue())
+				Expect(informer.Includes(configMap("kube-system", "new", nil))).To(BeFalse())
+			})
+		})
+
+		When("namespaces are excluded", func() {
+			BeforeEach(func() {
+				watchScope.ExcludeNamespaces = []string{"kube-system"}
+			})
+
+			It("ignores configmaps in those namespaces", func() {
+				Eventually(receivedNames).Should(ConsistOf("team-a/a", "team-b/b"))
+				Consistently(receivedNames).ShouldNot(ContainElement("kube-system/c"))
+			})
+
+			It("does not include configmaps in those namespaces", func() {
+				Expect(informer.Includes(configMap("team-a", "new", nil))).To(BeTrue())
+				Expect(informer.Includes(configMap("kube-system", "new", nil))).To(BeFalse())
+			})
+
+			It("does not get configmaps in those namespaces", func() {
+				configMap, err := informer.Get("team-a", "a")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(configMap.Name).To(Equal("a"))
+
+				_, err = informer.Get("kube-system", "c")
+				Expect(apierrors.IsNotFound(err)).To(BeTrue())
+			})
+
+			It("excludes them on the API server", func() {
+				var selectors []string
+				for _, action := range fakeClient.Actions() {
+					if list, ok := action.(k8stesting.ListAction); ok && list.GetResource().Resource == "configmaps" {
+						selectors = append(selectors, list.GetListRestrictions().Fields.String())
+					}
+				}
+				Expect(selectors).To(ConsistOf("metadata.namespace!=kube-system"))
+			})
+		})
+
+		When("a label selector is set", func() {
+			BeforeEach(func() {
+				watchScope.LabelSelector = "opt-in=true"
+			})
+
+			It("only receives matching configmaps", func() {
+				Eventually(receivedNames).Should(ConsistOf("team-a/a"))
+				Consistently(receivedNames).Should(ConsistOf("team-a/a"))
+			})
+
+			It("only includes matching configmaps", func() {
+				Expect(informer.Includes(configMap("team-b", "new", map[string]string{"opt-in": "true"}))).To(BeTrue())
+				Expect(informer.Includes(configMap("team-b", "new", nil))).To(BeFalse())
+			})
+		})
+
+		When("a namespace label selector is set", func() {
+			BeforeEach(func() {
+				watchScope.NamespaceSelector = "curl-me=true"
+			})
+
+			It("includes configmaps in matching namespaces", func() {
+				Expect(informer.Includes(configMap("team-a", "new", nil))).To(BeTrue())
+				Expect(informer.Includes(configMap("team-b", "new", nil))).To(BeFalse())
+			})
+
+			It("only receives configmaps in matching namespaces", func() {
+				Eventually(receivedNames).Should(ContainElement("team-a/a"))
+				Consistently(receivedNames).Should(ConsistOf("team-a/a"))
+			})
+
+			It("receives existing configmaps once their namespace starts matching", func() {
+				Eventually(receivedNames).Should(ConsistOf("team-a/a"))
+
+				_, err := fakeClient.CoreV1().Namespaces().Create(&apiv1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{Name: "kube-system", Labels: map[string]string{"curl-me": "true"}},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(receivedNames).Should(ConsistOf("team-a/a", "kube-system/c"))
+			})
+		})
+	})
+
+	Describe("MetadataInformer", func() {
+		var (
+			fakeClient     *fake.Clientset
+			metadataClient *metadatafake.FakeMetadataClient
+			informer       *scope.Informer
+			stopCh         chan struct{}
+			mu             sync.Mutex
+			received       []string
+			annotationKey  = "my-annotation"
+		)
+
+		BeforeEach(func() {
+			received = nil
+			annotated := configMapWithData("team-a", "annotated", map[string]string{annotationKey: "key=example.com"})
+			plain := configMapWithData("team-a", "plain", nil)
+
+			fakeClient = fake.NewSimpleClientset(annotated, plain)
+			metadataClient = metadatafake.NewSimpleMetadataClient(metadataScheme(), toMetadata(annotated), toMetadata(plain))
+
+			var err error
+			informer, err = scope.NewMetadataInformer(fakeClient, metadataClient, scope.Scope{}, 0, annotationKey, cache.ResourceEventHandlerFuncs{
+				AddFunc: func(obj interface{}) {
+					mu.Lock()
+					defer mu.Unlock()
+					accessor, err := meta.Accessor(obj)
+					Expect(err).NotTo(HaveOccurred())
+					received = append(received, accessor.GetName())
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			stopCh = make(chan struct{})
+			go informer.Run(stopCh)
+			Eventually(informer.HasSynced).Should(BeTrue())
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+		})
+
+		It("only passes on annotated configmaps", func() {
+			receivedNames := func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string{}, received...)
+			}
+
+			Eventually(receivedNames).Should(ConsistOf("annotated"))
+			Consistently(receivedNames).Should(ConsistOf("annotated"))
+		})
+
+		It("gets cached configmaps in full", func() {
+			configMap, err := informer.Get("team-a", "annotated")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(configMap.Data).To(HaveKeyWithValue("payload", "some data"))
+
+			_, err = informer.Get("team-a", "missing")
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+	})
+
+	Describe("List", func() {
+		var fakeClient *fake.Clientset
+
+		names := func(configMaps []apiv1.ConfigMap) []string {
+			var names []string
+			for _, configMap := range configMaps {
+				names = append(names, configMap.Namespace+"/"+configMap.Name)
+			}
+			return names
+		}
+
+		BeforeEach(func() {
+			fakeClient = fake.NewSimpleClientset(
+				&apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"curl-me": "true"}}},
+				&apiv1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+				&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "a", Labels: map[string]string{"opt-in": "true"}}},
+				&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "b"}},
+				&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "c"}},
+			)
+		})
+
+		It("lists every configmap for the zero value", func() {
+			configMaps, err := scope.List(fakeClient, scope.Scope{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(configMaps)).To(ConsistOf("team-a/a", "team-b/b", "kube-system/c"))
+		})
+
+		It("applies the namespaces, exclusions and selectors", func() {
+			configMaps, err := scope.List(fakeClient, scope.Scope{Namespaces: []string{"team-a", "team-b"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(configMaps)).To(ConsistOf("team-a/a", "team-b/b"))
+
+			configMaps, err = scope.List(fakeClient, scope.Scope{ExcludeNamespaces: []string{"kube-system"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(configMaps)).To(ConsistOf("team-a/a", "team-b/b"))
+
+			configMaps, err = scope.List(fakeClient, scope.Scope{LabelSelector: "opt-in=true"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(configMaps)).To(ConsistOf("team-a/a"))
+
+			configMaps, err = scope.List(fakeClient, scope.Scope{NamespaceSelector: "curl-me=true"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(names(configMaps)).To(ConsistOf("team-a/a"))
+		})
+
+		It("rejects an invalid scope", func() {
+			_, err := scope.List(fakeClient, scope.Scope{Namespaces: []string{"a"}, ExcludeNamespaces: []string{"a"}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+func configMapWithData(namespace, name string, annotations map[string]string) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Annotations: annotations,
+		},
+		Data: map[string]string{
+			"payload": "some data",
+		},
+	}
+}
+
+func metadataScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	return scheme
+}
+
+func toMetadata(configMap *apiv1.ConfigMap) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+		},
+		ObjectMeta: configMap.ObjectMeta,
+	}
+}
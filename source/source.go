@@ -0,0 +1,146 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// AllowAnnotation on a source ConfigMap or Secret lists the namespaces,
+	// separated by commas, its data may be copied into. Wildcards such as
+	// team-* are supported.
+	AllowAnnotation = "x-k8s.io/allowed-namespaces"
+
+	// IndexName indexes the ConfigMaps copying from a source by Ref.IndexKey.
+	IndexName = "source"
+)
+
+// Ref points at a data key of a ConfigMap or Secret, written as
+// configmap://<namespace>/<name>/<key> or secret://<namespace>/<name>/<key>.
+type Ref struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Key       string
+}
+
+// IsRef reports whether u points at a ConfigMap or Secret.
+func IsRef(u *url.URL) bool {
+	return u.Scheme == "configmap" || u.Scheme == "secret"
+}
+
+func ParseRef(u *url.URL) (Ref, error) {
+	if !IsRef(u) {
+		return Ref{}, fmt.Errorf("url %s is neither a configmap:// nor a secret:// url", u)
+	}
+	parts := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if u.Host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return Ref{}, fmt.Errorf("invalid url %s, expected %s://<namespace>/<name>/<key>", u, u.Scheme)
+	}
+	return Ref{Kind: u.Scheme, Namespace: u.Host, Name: parts[0], Key: parts[1]}, nil
+}
+
+// IndexKey identifies the object r points at, ignoring the key.
+func (r Ref) IndexKey() string {
+	return r.Kind + "/" + r.Namespace + "/" + r.Name
+}
+
+func (r Ref) String() string {
+	return r.Kind + " " + r.Namespace + "/" + r.Name
+}
+
+// Fetcher copies data keys of ConfigMaps and Secrets that allow the namespace
+// they are fetched for, read from reconciler.NamespaceFrom. Sources can only
+// be in namespaces, every namespace when empty, matching what the Watcher
+// watches.
+type Fetcher struct {
+	clientset  kubernetes.Interface
+	namespaces []string
+}
+
+func NewFetcher(clientset kubernetes.Interface, namespaces []string) *Fetcher {
+	return &Fetcher{clientset: clientset, namespaces: namespaces}
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, u *url.URL, header http.Header) (*reconciler.Response, error) {
+	ref, err := ParseRef(u)
+	if err != nil {
+		return nil, err
+	}
+	namespace, ok := reconciler.NamespaceFrom(ctx)
+	if !ok {
+		return nil, fmt.Errorf("can't copy from %s, the namespace it is copied into is unknown", ref)
+	}
+	if !watched(f.namespaces, ref.Namespace) {
+		return nil, fmt.Errorf("can't copy from %s, namespace %s is not one of %s", ref, ref.Namespace, strings.Join(f.namespaces, ", "))
+	}
+
+	var (
+		meta  metav1.ObjectMeta
+		value []byte
+		found bool
+	)
+	switch ref.Kind {
+	case "secret":
+		secret, err := f.clientset.CoreV1().Secrets(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", ref, err)
+		}
+		meta = secret.ObjectMeta
+		value, found = secret.Data[ref.Key]
+	default:
+		configMap, err := f.clientset.CoreV1().ConfigMaps(ref.Namespace).Get(ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s: %v", ref, err)
+		}
+		meta = configMap.ObjectMeta
+		var data string
+		data, found = configMap.Data[ref.Key]
+		value = []byte(data)
+	}
+
+	if !Allows(meta.Annotations[AllowAnnotation], namespace) {
+		return nil, fmt.Errorf("%s does not allow copying into namespace %s, see its %s annotation", ref, namespace, AllowAnnotation)
+	}
+	if !found {
+		return nil, fmt.Errorf("%s has no data key %s", ref, ref.Key)
+	}
+	return &reconciler.Response{Body: value, ETag: meta.ResourceVersion}, nil
+}
+
+// Live is true as the Watcher requeues the ConfigMaps copying from a source
+// when it changes.
+func (f *Fetcher) Live() bool {
+	return true
+}
+
+func watched(namespaces []string, namespace string) bool {
+	if len(namespaces) == 0 {
+		return true
+	}
+	for _, watched := range namespaces {
+		if watched == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the AllowAnnotation value allowed lists namespace.
+func Allows(allowed, namespace string) bool {
+	for _, pattern := range strings.Split(allowed, ",") {
+		if ok, _ := path.Match(strings.TrimSpace(pattern), namespace); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package source_test
+
+import (
+	"context"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/source"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("ParseRef", func() {
+	It("splits the namespace, name and key", func() {
+		u, err := url.Parse("secret://platform/ca/bundle.pem")
+		Expect(err).NotTo(HaveOccurred())
+		ref, err := source.ParseRef(u)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref).To(Equal(source.Ref{Kind: "secret", Namespace: "platform", Name: "ca", Key: "bundle.pem"}))
+		Expect(ref.IndexKey()).To(Equal("secret/platform/ca"))
+	})
+
+	DescribeTable("rejecting invalid urls",
+		func(rawURL, expectedErr string) {
+			u, err := url.Parse(rawURL)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = source.ParseRef(u)
+			Expect(err).To(MatchError(expectedErr))
+		},
+		Entry("other scheme", "https://platform/ca/bundle.pem", "url https://platform/ca/bundle.pem is neither a configmap:// nor a secret:// url"),
+		Entry("missing key", "configmap://platform/ca", "invalid url configmap://platform/ca, expected configmap://<namespace>/<name>/<key>"),
+		Entry("too many segments", "configmap://platform/ca/bundle/pem", "invalid url configmap://platform/ca/bundle/pem, expected configmap://<namespace>/<name>/<key>"),
+	)
+})
+
+var _ = Describe("Allows", func() {
+	DescribeTable("matching namespaces",
+		func(allowed, namespace string, expected bool) {
+			Expect(source.Allows(allowed, namespace)).To(Equal(expected))
+		},
+		Entry("listed", "team-a, team-b", "team-b", true),
+		Entry("wildcard", "team-*", "team-c", true),
+		Entry("everything", "*", "team-c", true),
+		Entry("not listed", "team-a", "team-b", false),
+		Entry("no annotation", "", "team-a", false),
+	)
+})
+
+var _ = Describe("Fetcher", func() {
+	var (
+		fetcher *source.Fetcher
+		ctx     context.Context
+	)
+
+	BeforeEach(func() {
+		fetcher = source.NewFetcher(fake.NewSimpleClientset(
+			&apiv1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "ca",
+					Namespace:       "platform",
+					Annotations:     map[string]string{source.AllowAnnotation: "team-*"},
+					ResourceVersion: "7",
+				},
+				Data: map[string]string{"bundle.pem": "a ca bundle"},
+			},
+			&apiv1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "token",
+					Namespace:   "platform",
+					Annotations: map[string]string{source.AllowAnnotation: "team-a"},
+				},
+				Data: map[string][]byte{"token": []byte("a token")},
+			},
+		), []string{"platform", "team-a"})
+		ctx = reconciler.WithNamespace(context.Background(), "team-a")
+	})
+
+	fetch := func(ctx context.Context, rawURL string) (*reconciler.Response, error) {
+		u, err := url.Parse(rawURL)
+		Expect(err).NotTo(HaveOccurred())
+		return fetcher.Fetch(ctx, u, nil)
+	}
+
+	It("copies data keys of configmaps and secrets", func() {
+		resp, err := fetch(ctx, "configmap://platform/ca/bundle.pem")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(resp.Body)).To(Equal("a ca bundle"))
+		Expect(resp.ETag).To(Equal("7"))
+
+		resp, err = fetch(ctx, "secret://platform/token/token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(resp.Body)).To(Equal("a token"))
+	})
+
+	It("refuses namespaces the source doesn't allow", func() {
+		_, err := fetch(reconciler.WithNamespace(context.Background(), "team-b"), "secret://platform/token/token")
+		Expect(err).To(MatchError("secret platform/token does not allow copying into namespace team-b, see its x-k8s.io/allowed-namespaces annotation"))
+
+		_, err = fetch(context.Background(), "secret://platform/token/token")
+		Expect(err).To(MatchError("can't copy from secret platform/token, the namespace it is copied into is unknown"))
+	})
+
+	It("refuses sources outside of the watched namespaces", func() {
+		_, err := fetch(ctx, "configmap://kube-system/ca/bundle.pem")
+		Expect(err).To(MatchError("can't copy from configmap kube-system/ca, namespace kube-system is not one of platform, team-a"))
+	})
+
+	It("fails for missing sources and keys", func() {
+		_, err := fetch(ctx, "configmap://platform/missing/bundle.pem")
+		Expect(err).To(MatchError(ContainSubstring("failed to get configmap platform/missing")))
+
+		_, err = fetch(ctx, "configmap://platform/ca/missing")
+		Expect(err).To(MatchError("configmap platform/ca has no data key missing"))
+	})
+})
+
+var _ = Describe("IndexFunc", func() {
+	index := source.IndexFunc("my-annotation")
+
+	It("indexes configmaps by the source they copy from", func() {
+		keys, err := index(&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{"my-annotation": "ca.pem=configmap://platform/ca/bundle.pem"},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(Equal([]string{"configmap/platform/ca"}))
+	})
+
+	It("skips configmaps fetching urls or without a valid annotation", func() {
+		for _, annotations := range []map[string]string{
+			{"my-annotation": "ca.pem=https://example.com"},
+			{"my-annotation": "this looks wrong"},
+			nil,
+		} {
+			keys, err := index(&apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(BeEmpty())
+		}
+	})
+})
@@ -0,0 +1,144 @@
+package source
+
+import (
+	"strings"
+
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/reconciler"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/tools/cache"
+)
+
+// IndexFunc indexes ConfigMaps by the source their annotationKey annotation
+// copies from, for use with IndexName.
+func IndexFunc(annotationKey string) cache.IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, nil
+		}
+		annotation, ok := accessor.GetAnnotations()[annotationKey]
+		if !ok {
+			return nil, nil
+		}
+		// The policy is applied when fetching, the index only needs the url.
+		entry, err := reconciler.ParseAnnotation(annotation, reconciler.Policy{})
+		if err != nil || !IsRef(entry.URL) {
+			return nil, nil
+		}
+		ref, err := ParseRef(entry.URL)
+		if err != nil {
+			return nil, nil
+		}
+		return []string{ref.IndexKey()}, nil
+	}
+}
+
+// Targets looks up the ConfigMaps copying from a source in an index built
+// with IndexFunc.
+type Targets interface {
+	ByIndex(indexName, value string) ([]interface{}, error)
+}
+
+// Watcher watches the metadata of the ConfigMaps and Secrets in namespaces,
+// every namespace when empty, passing the keys of the ConfigMaps copying from
+// one to enqueue whenever it changes. Only metadata is cached, so Secret data
+// is never held in memory.
+type Watcher struct {
+	targets   Targets
+	enqueue   func(key string)
+	informers []cache.Controller
+}
+
+func NewWatcher(metadataClient metadata.Interface, namespaces []string, targets Targets, enqueue func(key string)) *Watcher {
+	if len(namespaces) == 0 {
+		namespaces = []string{apiv1.NamespaceAll}
+	}
+	w := &Watcher{targets: targets, enqueue: enqueue}
+	for _, namespace := range namespaces {
+		for _, kind := range []string{"configmap", "secret"} {
+			w.watch(metadataClient, namespace, kind)
+		}
+	}
+	return w
+}
+
+func (w *Watcher) watch(metadataClient metadata.Interface, namespace, kind string) {
+	resource := metadataClient.Resource(apiv1.SchemeGroupVersion.WithResource(kind + "s")).Namespace(namespace)
+	_, informer := cache.NewInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return resource.List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return resource.Watch(options)
+			},
+		},
+		&metav1.PartialObjectMetadata{},
+		0,
+		cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				w.changed(kind, obj)
+			},
+			UpdateFunc: func(_, obj interface{}) {
+				w.changed(kind, obj)
+			},
+			DeleteFunc: func(obj interface{}) {
+				w.changed(kind, obj)
+			},
+		},
+	)
+	w.informers = append(w.informers, informer)
+}
+
+func (w *Watcher) changed(kind string, obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+
+	targets, err := w.targets.ByIndex(IndexName, kind+"/"+namespace+"/"+name)
+	if err != nil {
+		log.Error("failed to look up the configmaps copying from %s %s: %v", kind, key, err)
+		return
+	}
+	for _, target := range targets {
+		targetKey, err := cache.MetaNamespaceKeyFunc(target)
+		if err != nil {
+			continue
+		}
+		log.Debug("%s %s changed, requeuing %s", kind, key, targetKey)
+		w.enqueue(targetKey)
+	}
+}
+
+func (w *Watcher) Run(stopCh <-chan struct{}) {
+	for _, informer := range w.informers {
+		go informer.Run(stopCh)
+	}
+	<-stopCh
+}
+
+func (w *Watcher) HasSynced() bool {
+	for _, informer := range w.informers {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *Watcher) LastSyncResourceVersion() string {
+	var versions []string
+	for _, informer := range w.informers {
+		versions = append(versions, informer.LastSyncResourceVersion())
+	}
+	return strings.Join(versions, ",")
+}
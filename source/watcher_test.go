@@ -0,0 +1,85 @@
+package source_test
+
+import (
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/source"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+var _ = Describe("Watcher", func() {
+	var (
+		metadataClient *metadatafake.FakeMetadataClient
+		stopCh         chan struct{}
+		mu             sync.Mutex
+		enqueued       []string
+	)
+
+	enqueuedKeys := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, enqueued...)
+	}
+
+	BeforeEach(func() {
+		enqueued = nil
+		targets := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{source.IndexName: source.IndexFunc("my-annotation")})
+		Expect(targets.Add(target("team-a", "copies-ca", "configmap://platform/ca/bundle.pem"))).To(Succeed())
+		Expect(targets.Add(target("team-b", "copies-token", "secret://platform/token/token"))).To(Succeed())
+		Expect(targets.Add(target("team-c", "fetches", "https://example.com"))).To(Succeed())
+		Expect(targets.Add(target("team-d", "copies-unwatched", "configmap://kube-system/ca/bundle.pem"))).To(Succeed())
+
+		scheme := runtime.NewScheme()
+		metav1.AddMetaToScheme(scheme)
+		metadataClient = metadatafake.NewSimpleMetadataClient(scheme,
+			metadata("ConfigMap", "platform", "ca"),
+			metadata("ConfigMap", "platform", "unused"),
+			metadata("Secret", "platform", "token"),
+			metadata("ConfigMap", "kube-system", "ca"),
+		)
+
+		watcher := source.NewWatcher(metadataClient, []string{"platform"}, targets, func(key string) {
+			mu.Lock()
+			defer mu.Unlock()
+			enqueued = append(enqueued, key)
+		})
+		stopCh = make(chan struct{})
+		go watcher.Run(stopCh)
+		Eventually(watcher.HasSynced).Should(BeTrue())
+	})
+
+	AfterEach(func() {
+		close(stopCh)
+	})
+
+	It("requeues the configmaps copying from a source in the watched namespaces when it changes", func() {
+		Eventually(enqueuedKeys).Should(ConsistOf("team-a/copies-ca", "team-b/copies-token"))
+
+		secrets := metadataClient.Resource(apiv1.SchemeGroupVersion.WithResource("secrets")).Namespace("platform")
+		Expect(secrets.Delete("token", nil)).To(Succeed())
+		Eventually(enqueuedKeys).Should(ConsistOf("team-a/copies-ca", "team-b/copies-token", "team-b/copies-token"))
+	})
+})
+
+func target(namespace, name, rawURL string) *apiv1.ConfigMap {
+	return &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Annotations: map[string]string{"my-annotation": "key=" + rawURL},
+	}}
+}
+
+func metadata(kind, namespace, name string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: kind},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+}
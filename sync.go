@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aclevername/config-map-controller/config"
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/metrics"
+	"github.com/aclevername/config-map-controller/oneshot"
+	"github.com/aclevername/config-map-controller/redact"
+	"github.com/aclevername/config-map-controller/replicate"
+	"github.com/aclevername/config-map-controller/scope"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// syncOnce reconciles every ConfigMap in scope once and reports the outcome,
+// returning the exit code.
+func syncOnce(args []string) int {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	kubeconfig := flags.String("kubeconfig", "", "path to kubeconfig, defaults to the in-cluster config")
+	configFile := flags.String("config", "", "path to a ControllerConfig file, replacing the scope, policy and history flags")
+	historyRevisions := flags.Int("history-revisions", 0, "keep that many fetched revisions of each data key in a companion configmap for rollbacks, 0 to disable")
+	concurrency := flags.Int("concurrency", 4, "how many configmaps to reconcile at once")
+	dryRun := flags.Bool("dry-run", false, "fetch but only log and record Normal events for the updates that would be made, reporting them as updated")
+	format := flags.String("format", oneshot.FormatText, "report format, text or json")
+	scopeFromFlags := scopeFlags(flags)
+	policyFromFlags := policyFlags(flags)
+	featuresFromFlags := featureFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s sync [flags]\n\nReconciles every ConfigMap in scope once, prints a report and exits non-zero if any failed.\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	redactor := redact.New(redact.DefaultQueryParams, redact.DefaultHeaders)
+	log.SetRedactor(redactor.String)
+
+	cfg, err := loadConfig(flags, *configFile, func() config.Config {
+		cfg := config.Default()
+		cfg.Scope.Scope = scopeFromFlags()
+		cfg.Policy = policyFromFlags()
+		cfg.History.Revisions = *historyRevisions
+		return cfg
+	})
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	if *format != oneshot.FormatText && *format != oneshot.FormatJSON {
+		log.Error("unknown format '%s', expected %s or %s", *format, oneshot.FormatText, oneshot.FormatJSON)
+		return 1
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+	if err != nil {
+		log.Error("failed to build client config: %v", err)
+		return 1
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		log.Error("failed to build kube client: %v", err)
+		return 1
+	}
+
+	configMaps, err := scope.List(clientset, cfg.Scope.Scope)
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	sources := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{replicate.IndexName: replicate.IndexFunc})
+	for i := range configMaps {
+		if err := sources.Add(&configMaps[i]); err != nil {
+			log.Error("failed to index configmaps to replicate: %v", err)
+			return 1
+		}
+	}
+	// Every configmap is reconciled once anyway, so there is nothing to
+	// requeue.
+	r, replicator := newReconciler(clientset, cfg, featuresFromFlags(), *dryRun, metrics.New(), redactor, sources, func(string) {})
+	if replicator != nil {
+		go replicator.Run(ctx.Done())
+		if !cache.WaitForCacheSync(ctx.Done(), replicator.HasSynced) {
+			log.Error("interrupted before namespaces were listed")
+			return 1
+		}
+	}
+
+	report := oneshot.Run(ctx, configMaps, cfg.Annotation.Key, oneshot.ReconcilerFunc(r.Sync), *concurrency)
+	if err := report.Write(os.Stdout, *format); err != nil {
+		log.Error("failed to write report: %v", err)
+		return 1
+	}
+
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aclevername/config-map-controller/config"
+	"github.com/aclevername/config-map-controller/lint"
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/metrics"
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/redact"
+	"github.com/aclevername/config-map-controller/source"
+	"github.com/aclevername/config-map-controller/webhook"
+)
+
+// validate checks the ConfigMap manifests in the given files and directories
+// without a cluster, returning the exit code.
+func validate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	format := flags.String("format", lint.FormatText, "output format, text, json or junit")
+	configFile := flags.String("config", "", "path to a ControllerConfig file to take the annotation key and policy from, replacing the policy flags")
+	objectSources := flags.Bool("object-sources", false, "accept configmap:// and secret:// urls, as the controller does with -object-sources")
+	policyFromFlags := policyFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s validate [flags] path...\n\nChecks the %s annotation, or the one set in -config, on ConfigMaps in yaml and json files, descending into directories.\n\n", os.Args[0], annotation)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 1
+	}
+
+	cfg, err := loadConfig(flags, *configFile, func() config.Config {
+		cfg := config.Default()
+		cfg.Policy = policyFromFlags()
+		return cfg
+	})
+	if err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	// Nothing is fetched, the reconciler only provides the registry the
+	// controller parses annotations with.
+	redactor := redact.New(redact.DefaultQueryParams, redact.DefaultHeaders)
+	r := reconciler.New(nil, cfg.Annotation.Key, metrics.New(), redactor, cfg.Policy)
+	if *objectSources {
+		r.RegisterFetcher(source.NewFetcher(nil, nil), "configmap", "secret")
+	}
+	validator := webhook.NewValidator(cfg.Annotation.Key, r.Fetchers(), cfg.Policy, redactor)
+	results := lint.Paths(flags.Args(), validator)
+	if err := lint.Write(os.Stdout, *format, results); err != nil {
+		log.Error("%v", err)
+		return 1
+	}
+
+	if lint.Failures(results) > 0 {
+		return 1
+	}
+	return 0
+}
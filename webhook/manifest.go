@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namespaceNameLabel is set on every namespace by the api server to its name.
+const namespaceNameLabel = "kubernetes.io/metadata.name"
+
+// ManifestConfig describes the service the webhook is reachable through.
+type ManifestConfig struct {
+	Name             string
+	ServiceName      string
+	ServiceNamespace string
+	CABundle         []byte
+	FailurePolicy    string
+	TimeoutSeconds   int32
+}
+
+// ValidatingWebhookConfiguration returns the configuration registering the
+// Validator for configmap creates and updates.
+func ValidatingWebhookConfiguration(config ManifestConfig) (*admissionregistrationv1.ValidatingWebhookConfiguration, error) {
+	failurePolicy, err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "ValidatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: config.Name,
+		},
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{
+			{
+				Name:                    "validate.curl-me-that.x-k8s.io",
+				ClientConfig:            config.clientConfig(ValidatePath),
+				Rules:                   configMapRules(admissionregistrationv1.Create, admissionregistrationv1.Update),
+				NamespaceSelector:       config.namespaceSelector(),
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				TimeoutSeconds:          &config.TimeoutSeconds,
+				AdmissionReviewVersions: []string{"v1", "v1beta1"},
+			},
+		},
+	}, nil
+}
+
+// MutatingWebhookConfiguration returns the configuration registering the
+// Mutator for configmap creates. Its failure policy is always Ignore, as the
+// controller fills in configmaps the mutator could not.
+func MutatingWebhookConfiguration(config ManifestConfig) (*admissionregistrationv1.MutatingWebhookConfiguration, error) {
+	if _, err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	failurePolicy := admissionregistrationv1.Ignore
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	reinvocationPolicy := admissionregistrationv1.NeverReinvocationPolicy
+	return &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+			Kind:       "MutatingWebhookConfiguration",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: config.Name,
+		},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{
+				Name:                    "prefetch.curl-me-that.x-k8s.io",
+				ClientConfig:            config.clientConfig(MutatePath),
+				Rules:                   configMapRules(admissionregistrationv1.Create),
+				NamespaceSelector:       config.namespaceSelector(),
+				FailurePolicy:           &failurePolicy,
+				SideEffects:             &sideEffects,
+				TimeoutSeconds:          &config.TimeoutSeconds,
+				AdmissionReviewVersions: []string{"v1", "v1beta1"},
+				ReinvocationPolicy:      &reinvocationPolicy,
+			},
+		},
+	}, nil
+}
+
+func (c ManifestConfig) validate() (admissionregistrationv1.FailurePolicyType, error) {
+	if c.Name == "" || c.ServiceName == "" || c.ServiceNamespace == "" {
+		return "", errors.New("name, service name and service namespace are required")
+	}
+
+	failurePolicy := admissionregistrationv1.FailurePolicyType(c.FailurePolicy)
+	if failurePolicy != admissionregistrationv1.Ignore && failurePolicy != admissionregistrationv1.Fail {
+		return "", fmt.Errorf("unknown failure policy '%s', expected %s or %s", c.FailurePolicy, admissionregistrationv1.Ignore, admissionregistrationv1.Fail)
+	}
+
+	if c.TimeoutSeconds < 1 || c.TimeoutSeconds > 30 {
+		return "", fmt.Errorf("timeout must be between 1 and 30 seconds, got %d", c.TimeoutSeconds)
+	}
+	return failurePolicy, nil
+}
+
+func (c ManifestConfig) clientConfig(path string) admissionregistrationv1.WebhookClientConfig {
+	return admissionregistrationv1.WebhookClientConfig{
+		Service: &admissionregistrationv1.ServiceReference{
+			Name:      c.ServiceName,
+			Namespace: c.ServiceNamespace,
+			Path:      &path,
+		},
+		CABundle: c.CABundle,
+	}
+}
+
+// namespaceSelector excludes kube-system and the controller's own namespace, so
+// an unavailable webhook can't block the configmaps the cluster or the
+// controller need to recover.
+func (c ManifestConfig) namespaceSelector() *metav1.LabelSelector {
+	excluded := []string{metav1.NamespaceSystem}
+	if c.ServiceNamespace != metav1.NamespaceSystem {
+		excluded = append(excluded, c.ServiceNamespace)
+	}
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{
+				Key:      namespaceNameLabel,
+				Operator: metav1.LabelSelectorOpNotIn,
+				Values:   excluded,
+			},
+		},
+	}
+}
+
+func configMapRules(operations ...admissionregistrationv1.OperationType) []admissionregistrationv1.RuleWithOperations {
+	scope := admissionregistrationv1.NamespacedScope
+	return []admissionregistrationv1.RuleWithOperations{
+		{
+			Operations: operations,
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"configmaps"},
+				Scope:       &scope,
+			},
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package webhook_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/webhook"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("ValidatingWebhookConfiguration", func() {
+	var config webhook.ManifestConfig
+
+	BeforeEach(func() {
+		config = webhook.ManifestConfig{
+			Name:             "config-map-controller",
+			ServiceName:      "config-map-controller-webhook",
+			ServiceNamespace: "kube-system",
+			CABundle:         []byte("ca"),
+			FailurePolicy:    "Fail",
+			TimeoutSeconds:   5,
+		}
+	})
+
+	It("registers the validator for configmap creates and updates", func() {
+		configuration, err := webhook.ValidatingWebhookConfiguration(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configuration.APIVersion).To(Equal("admissionregistration.k8s.io/v1"))
+		Expect(configuration.Kind).To(Equal("ValidatingWebhookConfiguration"))
+		Expect(configuration.Name).To(Equal("config-map-controller"))
+		Expect(configuration.Webhooks).To(HaveLen(1))
+
+		validating := configuration.Webhooks[0]
+		Expect(validating.ClientConfig.Service.Name).To(Equal("config-map-controller-webhook"))
+		Expect(validating.ClientConfig.Service.Namespace).To(Equal("kube-system"))
+		Expect(*validating.ClientConfig.Service.Path).To(Equal(webhook.ValidatePath))
+		Expect(validating.ClientConfig.CABundle).To(Equal([]byte("ca")))
+		Expect(validating.Rules).To(HaveLen(1))
+		Expect(validating.Rules[0].Operations).To(ConsistOf(admissionregistrationv1.Create, admissionregistrationv1.Update))
+		Expect(validating.Rules[0].Resources).To(ConsistOf("configmaps"))
+		Expect(*validating.FailurePolicy).To(Equal(admissionregistrationv1.Fail))
+		Expect(*validating.SideEffects).To(Equal(admissionregistrationv1.SideEffectClassNone))
+		Expect(*validating.TimeoutSeconds).To(Equal(int32(5)))
+		Expect(validating.AdmissionReviewVersions).To(Equal([]string{"v1", "v1beta1"}))
+		Expect(validating.NamespaceSelector.MatchExpressions).To(ConsistOf(metav1.LabelSelectorRequirement{
+			Key:      "kubernetes.io/metadata.name",
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{"kube-system"},
+		}))
+	})
+
+	It("excludes the controller's namespace too", func() {
+		config.ServiceNamespace = "config-map-controller"
+		configuration, err := webhook.ValidatingWebhookConfiguration(config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configuration.Webhooks[0].NamespaceSelector.MatchExpressions).To(ConsistOf(metav1.LabelSelectorRequirement{
+			Key:      "kubernetes.io/metadata.name",
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{"kube-system", "config-map-controller"},
+		}))
+	})
+
+	It("rejects unknown failure policies", func() {
+		config.FailurePolicy = "Sometimes"
+		_, err := webhook.ValidatingWebhookConfiguration(config)
+		Expect(err).To(MatchError("unknown failure policy 'Sometimes', expected Ignore or Fail"))
+	})
+
+	It("rejects timeouts the api server won't accept", func() {
+		config.TimeoutSeconds = 31
+		_, err := webhook.ValidatingWebhookConfiguration(config)
+		Expect(err).To(MatchError("timeout must be between 1 and 30 seconds, got 31"))
+	})
+
+	It("requires the service", func() {
+		config.ServiceName = ""
+		_, err := webhook.ValidatingWebhookConfiguration(config)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("MutatingWebhookConfiguration", func() {
+	It("registers the mutator for configmap creates only, ignoring failures", func() {
+		configuration, err := webhook.MutatingWebhookConfiguration(webhook.ManifestConfig{
+			Name:             "config-map-controller",
+			ServiceName:      "config-map-controller-webhook",
+			ServiceNamespace: "config-map-controller",
+			FailurePolicy:    "Fail",
+			TimeoutSeconds:   5,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configuration.Kind).To(Equal("MutatingWebhookConfiguration"))
+		Expect(configuration.Webhooks).To(HaveLen(1))
+
+		mutating := configuration.Webhooks[0]
+		Expect(*mutating.ClientConfig.Service.Path).To(Equal(webhook.MutatePath))
+		Expect(mutating.Rules[0].Operations).To(ConsistOf(admissionregistrationv1.Create))
+		Expect(*mutating.FailurePolicy).To(Equal(admissionregistrationv1.Ignore))
+		Expect(*mutating.ReinvocationPolicy).To(Equal(admissionregistrationv1.NeverReinvocationPolicy))
+		Expect(mutating.NamespaceSelector.MatchExpressions).To(ConsistOf(metav1.LabelSelectorRequirement{
+			Key:      "kubernetes.io/metadata.name",
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{"kube-system", "config-map-controller"},
+		}))
+	})
+
+	It("validates the config", func() {
+		_, err := webhook.MutatingWebhookConfiguration(webhook.ManifestConfig{})
+		Expect(err).To(HaveOccurred())
+	})
+})
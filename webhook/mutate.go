@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/reconciler"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const MutatePath = "/prefetch-configmaps"
+
+//go:generate counterfeiter -o fakes/fake_fetcher.go . Fetcher
+
+type Fetcher interface {
+	Fetch(ctx context.Context, configMap *apiv1.ConfigMap) (reconciler.Fetched, error)
+}
+
+// Scope decides which configmaps the controller manages, such as a
+// scope.Informer.
+type Scope interface {
+	Includes(configMap metav1.Object) bool
+}
+
+// Mutator fetches the data for newly created configmaps in scope during
+// admission so they are complete from their first revision. When the fetch
+// fails or takes longer than timeout the configmap is admitted unchanged and
+// the controller fills it in later.
+type Mutator struct {
+	fetcher Fetcher
+	scope   Scope
+	timeout time.Duration
+}
+
+func NewMutator(fetcher Fetcher, scope Scope, timeout time.Duration) *Mutator {
+	return &Mutator{
+		fetcher: fetcher,
+		scope:   scope,
+		timeout: timeout,
+	}
+}
+
+func (m *Mutator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, m.review)
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+func (m *Mutator) review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	logger := log.With(log.Fields{"namespace": req.Namespace, "name": req.Name, "uid": req.UID})
+
+	if req.Operation != admissionv1.Create {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	configMap, err := decodeConfigMap(req)
+	if err != nil {
+		logger.Error("%v", err)
+		return &admissionv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	if !m.scope.Includes(configMap) {
+		logger.Debug("configmap is outside the watch scope, leaving it alone")
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+
+	start := time.Now()
+	fetched, err := m.fetcher.Fetch(ctx, configMap)
+	if err != nil {
+		logger.With(log.Fields{"error": err, "duration": time.Since(start)}).Info("prefetch failed, leaving the configmap to the controller")
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+	if fetched.Key == "" {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	var patch []patchOperation
+	if configMap.Data == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/data", Value: map[string]string{fetched.Key: fetched.Value}})
+	} else {
+		patch = append(patch, patchOperation{Op: "add", Path: "/data/" + fetched.Key, Value: fetched.Value})
+	}
+	// The controller records the key signed data was verified with, and
+	// would update the configmap again if it was missing.
+	if fetched.VerifiedKey != "" {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/annotations/" + escapePointer(reconciler.VerifiedKeyAnnotation), Value: fetched.VerifiedKey})
+	}
+
+	encoded, err := json.Marshal(patch)
+	if err != nil {
+		logger.Error("failed to encode patch: %v", err)
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	logger.With(log.Fields{"data_key": fetched.Key, "duration": time.Since(start)}).Debug("prefetched data")
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     encoded,
+		PatchType: &patchType,
+	}
+}
+
+// escapePointer escapes a key for use in a JSON pointer.
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,132 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/scope"
+	"github.com/aclevername/config-map-controller/webhook"
+	"github.com/aclevername/config-map-controller/webhook/fakes"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+var _ = Describe("Mutator", func() {
+	var (
+		server      *httptest.Server
+		fakeFetcher *fakes.FakeFetcher
+		watchScope  scope.Scope
+	)
+
+	BeforeEach(func() {
+		fakeFetcher = new(fakes.FakeFetcher)
+		fakeFetcher.FetchReturns(reconciler.Fetched{Key: "joke", Value: "a joke"}, nil)
+		watchScope = scope.Scope{}
+	})
+
+	JustBeforeEach(func() {
+		informer, err := scope.NewInformer(fake.NewSimpleClientset(), watchScope, 0, cache.ResourceEventHandlerFuncs{})
+		Expect(err).NotTo(HaveOccurred())
+		server = httptest.NewServer(webhook.NewMutator(fakeFetcher, informer, 50*time.Millisecond))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("adds the fetched data to the created configmap", func() {
+		review := postFixture(server.URL, "create-valid.json")
+		Expect(review.Response.UID).To(BeEquivalentTo("0df28fbd-5f5f-11e8-bc74-36e6bb280816"))
+		Expect(review.Response.Allowed).To(BeTrue())
+		Expect(*review.Response.PatchType).To(Equal(admissionv1.PatchTypeJSONPatch))
+		Expect(review.Response.Patch).To(MatchJSON(`[{"op": "add", "path": "/data", "value": {"joke": "a joke"}}]`))
+
+		Expect(fakeFetcher.FetchCallCount()).To(Equal(1))
+		_, configMap := fakeFetcher.FetchArgsForCall(0)
+		Expect(configMap.Name).To(Equal("valid"))
+		Expect(configMap.Annotations).To(HaveKeyWithValue("x-k8s.io/curl-me-that", "joke=curl-a-joke.herokuapp.com"))
+	})
+
+	It("adds the key to existing data", func() {
+		fakeFetcher.FetchReturns(reconciler.Fetched{Key: "key", Value: "a joke"}, nil)
+		review := postFixture(server.URL, "create-no-annotation.json")
+		Expect(review.Response.Patch).To(MatchJSON(`[{"op": "add", "path": "/data/key", "value": "a joke"}]`))
+	})
+
+	It("records the key signed data was verified with", func() {
+		fakeFetcher.FetchReturns(reconciler.Fetched{Key: "joke", Value: "a joke", VerifiedKey: "SHA256:abc"}, nil)
+		review := postFixture(server.URL, "create-valid.json")
+		Expect(review.Response.Patch).To(MatchJSON(`[
+			{"op": "add", "path": "/data", "value": {"joke": "a joke"}},
+			{"op": "add", "path": "/metadata/annotations/x-k8s.io~1verified-key", "value": "SHA256:abc"}
+		]`))
+	})
+
+	When("the configmap's namespace is outside the watch scope", func() {
+		BeforeEach(func() {
+			watchScope.Namespaces = []string{"team-a"}
+		})
+
+		It("admits it unchanged without fetching", func() {
+			review := postFixture(server.URL, "create-valid.json")
+			Expect(review.Response.Allowed).To(BeTrue())
+			Expect(review.Response.Patch).To(BeNil())
+			Expect(fakeFetcher.FetchCallCount()).To(Equal(0))
+		})
+	})
+
+	When("the configmap doesn't match the label selector", func() {
+		BeforeEach(func() {
+			watchScope.LabelSelector = "opt-in=true"
+		})
+
+		It("admits it unchanged without fetching", func() {
+			review := postFixture(server.URL, "create-valid.json")
+			Expect(review.Response.Allowed).To(BeTrue())
+			Expect(review.Response.Patch).To(BeNil())
+			Expect(fakeFetcher.FetchCallCount()).To(Equal(0))
+		})
+	})
+
+	It("admits the configmap unchanged when there is nothing to fetch", func() {
+		fakeFetcher.FetchReturns(reconciler.Fetched{}, nil)
+		review := postFixture(server.URL, "create-no-annotation.json")
+		Expect(review.Response.Allowed).To(BeTrue())
+		Expect(review.Response.Patch).To(BeNil())
+	})
+
+	It("admits the configmap unchanged when the fetch fails", func() {
+		fakeFetcher.FetchReturns(reconciler.Fetched{}, errors.New("failed to curl"))
+		review := postFixture(server.URL, "create-valid.json")
+		Expect(review.Response.Allowed).To(BeTrue())
+		Expect(review.Response.Patch).To(BeNil())
+	})
+
+	It("admits the configmap unchanged when the fetch takes longer than the timeout", func() {
+		fakeFetcher.FetchStub = func(ctx context.Context, configMap *apiv1.ConfigMap) (reconciler.Fetched, error) {
+			<-ctx.Done()
+			return reconciler.Fetched{}, ctx.Err()
+		}
+		start := time.Now()
+		review := postFixture(server.URL, "create-valid.json")
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(review.Response.Allowed).To(BeTrue())
+		Expect(review.Response.Patch).To(BeNil())
+	})
+
+	It("leaves updates alone", func() {
+		review := postFixture(server.URL, "update-v1beta1.json")
+		Expect(review.Response.Allowed).To(BeTrue())
+		Expect(review.Response.Patch).To(BeNil())
+		Expect(fakeFetcher.FetchCallCount()).To(Equal(0))
+	})
+})
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/aclevername/config-map-controller/log"
+	"github.com/aclevername/config-map-controller/reconciler"
+	"github.com/aclevername/config-map-controller/redact"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const ValidatePath = "/validate-configmaps"
+
+// Validator rejects configmaps whose annotation the reconciler would fail
+// on, so mistakes are reported by kubectl rather than as events later. fetchers
+// should be the registry of the reconciler, so the same schemes are accepted.
+type Validator struct {
+	annotationKey string
+	fetchers      reconciler.Registry
+	redactor      *redact.Redactor
+
+	mu     sync.RWMutex
+	policy reconciler.Policy
+}
+
+func NewValidator(annotationKey string, fetchers reconciler.Registry, policy reconciler.Policy, redactor *redact.Redactor) *Validator {
+	return &Validator{
+		annotationKey: annotationKey,
+		fetchers:      fetchers,
+		policy:        policy,
+		redactor:      redactor,
+	}
+}
+
+// SetPolicy replaces the policy urls are checked against. It is safe to call
+// while serving.
+func (v *Validator) SetPolicy(policy reconciler.Policy) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.policy = policy
+}
+
+func (v *Validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serve(w, r, v.review)
+}
+
+// Validate runs the checks the reconciler makes before fetching. ConfigMaps
+// without the annotation are always valid.
+func (v *Validator) Validate(configMap *apiv1.ConfigMap) error {
+	annotation, ok := configMap.Annotations[v.annotationKey]
+	if !ok {
+		return nil
+	}
+
+	v.mu.RLock()
+	policy := v.policy
+	v.mu.RUnlock()
+
+	if err := v.check(configMap, annotation, policy); err != nil {
+		return fmt.Errorf("invalid %s annotation: %s", v.annotationKey, v.redactor.String(err.Error()))
+	}
+	return nil
+}
+
+func (v *Validator) check(configMap *apiv1.ConfigMap, annotation string, policy reconciler.Policy) error {
+	entry, err := v.fetchers.ParseAnnotation(annotation, policy)
+	if err != nil {
+		return err
+	}
+	if err := v.fetchers.CheckScheme(entry.URL); err != nil {
+		return err
+	}
+	if entry.Signature != nil && entry.Signature.URL != nil {
+		if err := v.fetchers.CheckScheme(entry.Signature.URL); err != nil {
+			return err
+		}
+	}
+	return reconciler.CheckCollision(configMap, entry.Key)
+}
+
+func (v *Validator) review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	logger := log.With(log.Fields{"namespace": req.Namespace, "name": req.Name, "uid": req.UID})
+
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	configMap, err := decodeConfigMap(req)
+	if err != nil {
+		logger.Error("%v", err)
+		return &admissionv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	if err := v.Validate(configMap); err != nil {
+		logger.Info("denied configmap: %v", err)
+		return &admissionv1.AdmissionResponse{
+			Result: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Code:    http.StatusForbidden,
+				Reason:  metav1.StatusReasonInvalid,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
@@ -0,0 +1,143 @@
+package webhook_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/aclevername/config-map-controller/reconciler"
+	reconcilerfakes "github.com/aclevername/config-map-controller/reconciler/fakes"
+	"github.com/aclevername/config-map-controller/redact"
+	"github.com/aclevername/config-map-controller/webhook"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Validator", func() {
+	var (
+		server    *httptest.Server
+		validator *webhook.Validator
+		r         reconciler.ConfigMapReconciler
+	)
+
+	BeforeEach(func() {
+		r = reconciler.New(fake.NewSimpleClientset(), "x-k8s.io/curl-me-that", new(reconcilerfakes.FakeMetrics), redact.New(nil, nil), reconciler.Policy{})
+		validator = webhook.NewValidator(
+			"x-k8s.io/curl-me-that",
+			r.Fetchers(),
+			reconciler.Policy{AllowedSchemes: []string{"http", "https", "configmap"}, DeniedHosts: []string{"169.254.169.254"}},
+			redact.New(redact.DefaultQueryParams, redact.DefaultHeaders),
+		)
+		server = httptest.NewServer(validator)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("allows configmaps with a valid annotation", func() {
+		review := postFixture(server.URL, "create-valid.json")
+		Expect(review.APIVersion).To(Equal("admission.k8s.io/v1"))
+		Expect(review.Kind).To(Equal("AdmissionReview"))
+		Expect(review.Request).To(BeNil())
+		Expect(review.Response.UID).To(Equal(types.UID("0df28fbd-5f5f-11e8-bc74-36e6bb280816")))
+		Expect(review.Response.Allowed).To(BeTrue())
+	})
+
+	It("allows configmaps without the annotation", func() {
+		review := postFixture(server.URL, "create-no-annotation.json")
+		Expect(review.Response.Allowed).To(BeTrue())
+	})
+
+	It("answers v1beta1 reviews in v1beta1", func() {
+		review := postFixture(server.URL, "update-v1beta1.json")
+		Expect(review.APIVersion).To(Equal("admission.k8s.io/v1beta1"))
+		Expect(review.Response.UID).To(Equal(types.UID("6f708192-5f5f-11e8-bc74-36e6bb280816")))
+		Expect(review.Response.Allowed).To(BeTrue())
+	})
+
+	It("denies malformed annotations", func() {
+		review := postFixture(server.URL, "create-malformed.json")
+		Expect(review.Response.Allowed).To(BeFalse())
+		Expect(review.Response.Result.Code).To(Equal(int32(http.StatusForbidden)))
+		Expect(review.Response.Result.Message).To(Equal("invalid x-k8s.io/curl-me-that annotation: annotation value 'curl-a-joke.herokuapp.com' does not match expected format key=url"))
+	})
+
+	It("denies invalid data keys", func() {
+		review := postFixture(server.URL, "create-invalid-key.json")
+		Expect(review.Response.Allowed).To(BeFalse())
+		Expect(review.Response.Result.Message).To(ContainSubstring("invalid data key 'my/joke'"))
+	})
+
+	It("denies urls not allowed by the policy without leaking credentials", func() {
+		review := postFixture(server.URL, "create-denied-host.json")
+		Expect(review.Response.Allowed).To(BeFalse())
+		Expect(review.Response.Result.Message).To(ContainSubstring("host 169.254.169.254 is denied"))
+		Expect(review.Response.Result.Message).To(ContainSubstring("token=REDACTED"))
+		Expect(review.Response.Result.Message).NotTo(ContainSubstring("secret"))
+	})
+
+	It("applies a policy replaced while serving", func() {
+		validator.SetPolicy(reconciler.Policy{DeniedHosts: []string{"curl-a-joke.herokuapp.com"}})
+		review := postFixture(server.URL, "create-valid.json")
+		Expect(review.Response.Allowed).To(BeFalse())
+		Expect(review.Response.Result.Message).To(ContainSubstring("host curl-a-joke.herokuapp.com is denied"))
+	})
+
+	It("denies keys that collide with binaryData", func() {
+		review := postFixture(server.URL, "create-binary-collision.json")
+		Expect(review.Response.Allowed).To(BeFalse())
+		Expect(review.Response.Result.Message).To(ContainSubstring("data key 'joke' already exists in binaryData"))
+	})
+
+	It("accepts the sources the reconciler has fetchers for", func() {
+		configMap := &apiv1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "team-a",
+			Name:        "ca",
+			Annotations: map[string]string{"x-k8s.io/curl-me-that": "bundle.pem=configmap://platform/ca/bundle.pem"},
+		}}
+		Expect(validator.Validate(configMap)).To(MatchError(ContainSubstring("scheme configmap is not one of data, http, https")))
+
+		r.RegisterFetcher(new(reconcilerfakes.FakeFetcher), "configmap", "secret")
+		Expect(validator.Validate(configMap)).To(Succeed())
+	})
+
+	It("rejects requests that aren't admission reviews", func() {
+		resp, err := http.Post(server.URL, "application/json", bytes.NewBufferString(`{"kind": "AdmissionReview"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+
+		resp, err = http.Post(server.URL, "text/plain", bytes.NewBufferString(`{}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnsupportedMediaType))
+
+		resp, err = http.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
+
+func postFixture(url, fixture string) admissionv1.AdmissionReview {
+	body, err := ioutil.ReadFile(filepath.Join("fixtures", fixture))
+	Expect(err).NotTo(HaveOccurred())
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	Expect(err).NotTo(HaveOccurred())
+	defer resp.Body.Close()
+	Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+	var review admissionv1.AdmissionReview
+	Expect(json.NewDecoder(resp.Body).Decode(&review)).To(Succeed())
+	Expect(review.Response).NotTo(BeNil())
+	return review
+}
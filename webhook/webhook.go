@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	apiv1 "k8s.io/api/core/v1"
+)
+
+const maxRequestSize = 3 * 1024 * 1024
+
+type review func(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// serve decodes an AdmissionReview, passes its request to review and writes
+// the response back. admission.k8s.io/v1beta1 and v1 share the same shape so
+// both are handled, replying in the version of the request.
+func serve(w http.ResponseWriter, r *http.Request, review review) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		http.Error(w, "expected content type application/json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var admissionReview admissionv1.AdmissionReview
+	if err := json.Unmarshal(body, &admissionReview); err != nil {
+		http.Error(w, fmt.Sprintf("failed to decode admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+	if admissionReview.Request == nil {
+		http.Error(w, "admission review has no request", http.StatusBadRequest)
+		return
+	}
+
+	response := review(admissionReview.Request)
+	response.UID = admissionReview.Request.UID
+
+	if admissionReview.APIVersion == "" {
+		admissionReview.APIVersion = admissionv1.SchemeGroupVersion.String()
+		admissionReview.Kind = "AdmissionReview"
+	}
+	admissionReview.Request = nil
+	admissionReview.Response = response
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(admissionReview)
+}
+
+func decodeConfigMap(req *admissionv1.AdmissionRequest) (*apiv1.ConfigMap, error) {
+	var configMap apiv1.ConfigMap
+	if err := json.Unmarshal(req.Object.Raw, &configMap); err != nil {
+		return nil, fmt.Errorf("failed to decode configmap: %v", err)
+	}
+	// Objects being created don't always carry the namespace of the request.
+	if configMap.Namespace == "" {
+		configMap.Namespace = req.Namespace
+	}
+	return &configMap, nil
+}